@@ -3,36 +3,124 @@ package main
 import (
 	"context"
 	"fmt"
+	"os"
 
 	"github.com/conductorone/baton-sdk/pkg/cli"
 	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"github.com/conductorone/baton-elastic/pkg/connector"
+	"github.com/conductorone/baton-elastic/pkg/elastic"
 )
 
 // config defines the external configuration required for the connector to run.
 type config struct {
 	cli.BaseConfig `mapstructure:",squash"` // Puts the base config options in the same place as the connector options
 
-	ApiKey             string `mapstructure:"api-key"`
-	OrganizationID     string `mapstructure:"organization-id,omitempty"`
-	DeploymentApiKey   string `mapstructure:"deployment-api-key"`
-	DeploymentEndpoint string `mapstructure:"deployment-endpoint"`
+	ApiKey                string `mapstructure:"api-key"`
+	CloudApiURL           string `mapstructure:"cloud-api-url"`
+	ECE                   bool   `mapstructure:"ece"`
+	OrganizationID        string `mapstructure:"organization-id,omitempty"`
+	DeploymentApiKey      string `mapstructure:"deployment-api-key"`
+	DeploymentEndpoint    string `mapstructure:"deployment-endpoint"`
+	CloudID               string `mapstructure:"cloud-id"`
+	DeploymentUsername    string `mapstructure:"deployment-username"`
+	DeploymentPassword    string `mapstructure:"deployment-password"`
+	DeploymentBearerToken string `mapstructure:"deployment-bearer-token"`
+	DeploymentCertFile    string `mapstructure:"deployment-cert-file"`
+	DeploymentKeyFile     string `mapstructure:"deployment-key-file"`
+	DeploymentCAFile      string `mapstructure:"deployment-ca-file"`
+	UseCloudProxy         bool   `mapstructure:"use-cloud-proxy"`
+	DeploymentsFile       string `mapstructure:"deployments-file"`
+}
+
+// deploymentsFile is the format of the YAML or JSON file listing the deployments to sync.
+type deploymentsFile struct {
+	Deployments []connector.DeploymentConfig `yaml:"deployments" json:"deployments"`
 }
 
 // validateConfig is run after the configuration is loaded, and should return an error if it isn't valid.
 func validateConfig(ctx context.Context, cfg *config) error {
-	if cfg.ApiKey == "" {
+	if cfg.ApiKey == "" && cfg.DeploymentEndpoint == "" && cfg.CloudID == "" && cfg.DeploymentsFile == "" {
 		return fmt.Errorf("api key is missing, please provide it via --api-key flag or $BATON_API_KEY environment variable")
 	}
 
+	if cfg.ApiKey == "" && cfg.ECE {
+		return fmt.Errorf("api key is required to sync an Elastic Cloud Enterprise installation")
+	}
+
+	if cfg.ApiKey == "" && cfg.UseCloudProxy {
+		return fmt.Errorf("api key is required to sync deployments through the Elastic cloud API proxy")
+	}
+
 	return nil
 }
 
+// endpointDeployment returns the deployment configured with the --deployment-* flags, if any.
+func endpointDeployment(cfg *config) []connector.DeploymentConfig {
+	if cfg.DeploymentEndpoint == "" && cfg.CloudID == "" {
+		return nil
+	}
+
+	return []connector.DeploymentConfig{{
+		Endpoint:    cfg.DeploymentEndpoint,
+		CloudID:     cfg.CloudID,
+		APIKey:      cfg.DeploymentApiKey,
+		Username:    cfg.DeploymentUsername,
+		Password:    cfg.DeploymentPassword,
+		BearerToken: cfg.DeploymentBearerToken,
+		CertFile:    cfg.DeploymentCertFile,
+		KeyFile:     cfg.DeploymentKeyFile,
+		CAFile:      cfg.DeploymentCAFile,
+	}}
+}
+
+// loadDeploymentsFile reads the deployments to sync from a YAML or JSON file.
+func loadDeploymentsFile(path string) ([]connector.DeploymentConfig, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading deployments file: %w", err)
+	}
+
+	// YAML is a superset of JSON, so both formats are parsed the same way.
+	var file deploymentsFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("error parsing deployments file: %w", err)
+	}
+
+	if len(file.Deployments) == 0 {
+		return nil, fmt.Errorf("deployments file %s lists no deployments", path)
+	}
+
+	return file.Deployments, nil
+}
+
 // cmdFlags sets the cmdFlags required for the connector.
 func cmdFlags(cmd *cobra.Command) {
 	cmd.PersistentFlags().String("api-key", "", "Elastic API key used to communicate with Elastic cloud API. ($BATON_API_KEY)")
+	cmd.PersistentFlags().String("cloud-api-url", elastic.DefaultBaseURL, "Base URL of the Elastic cloud API, e.g. the API of your Elastic Cloud Enterprise installation. ($BATON_CLOUD_API_URL)")
+	cmd.PersistentFlags().Bool("ece", false, "Sync platform users and roles of the Elastic Cloud Enterprise installation at --cloud-api-url instead of Elastic cloud organizations. ($BATON_ECE)")
 	cmd.PersistentFlags().String("organization-id", "", "Optional. Provide your Elastic organization ID if you want to sync members of a single organization. ($BATON_ORGANIZATION_ID)")
 	cmd.PersistentFlags().String("deployment-api-key", "", "API key of your elasticsearch deployment. ($BATON_DEPLOYMENT_API_KEY)")
 	cmd.PersistentFlags().String("deployment-endpoint", "", "Elasticsearch endpoint used to sync deployment resources. ($BATON_DEPLOYMENT_ENDPOINT)")
+	cmd.PersistentFlags().String("cloud-id", "", "Cloud ID of your elasticsearch deployment, used instead of --deployment-endpoint. ($BATON_CLOUD_ID)")
+	cmd.PersistentFlags().String("deployment-username", "", "Username used to authenticate with HTTP basic auth to your elasticsearch deployment. ($BATON_DEPLOYMENT_USERNAME)")
+	cmd.PersistentFlags().String("deployment-password", "", "Password used to authenticate with HTTP basic auth to your elasticsearch deployment. ($BATON_DEPLOYMENT_PASSWORD)")
+	cmd.PersistentFlags().String("deployment-bearer-token", "", "OAuth access token from the _security/oauth2/token API of your elasticsearch deployment. ($BATON_DEPLOYMENT_BEARER_TOKEN)")
+	cmd.PersistentFlags().String("deployment-cert-file", "", "PEM encoded TLS client certificate used to authenticate with the PKI realm of your elasticsearch deployment. ($BATON_DEPLOYMENT_CERT_FILE)")
+	cmd.PersistentFlags().String("deployment-key-file", "", "PEM encoded key of the TLS client certificate. ($BATON_DEPLOYMENT_KEY_FILE)")
+	cmd.PersistentFlags().String("deployment-ca-file", "", "PEM encoded CA bundle trusted when connecting to your elasticsearch deployment. ($BATON_DEPLOYMENT_CA_FILE)")
+
+	cmd.PersistentFlags().Bool("use-cloud-proxy", false, "Sync users and roles of every deployment through the Elastic cloud API proxy, authenticated with --api-key. ($BATON_USE_CLOUD_PROXY)")
+
+	cmd.PersistentFlags().String("deployments-file", "", "Path to a YAML or JSON file listing elasticsearch deployments to sync, each with its own endpoint and API key. ($BATON_DEPLOYMENTS_FILE)")
 
-	cmd.MarkFlagsRequiredTogether("deployment-api-key", "deployment-endpoint")
+	cmd.MarkFlagsMutuallyExclusive("deployment-endpoint", "cloud-id")
+	cmd.MarkFlagsRequiredTogether("deployment-username", "deployment-password")
+	cmd.MarkFlagsRequiredTogether("deployment-cert-file", "deployment-key-file")
+	cmd.MarkFlagsMutuallyExclusive("deployment-api-key", "deployment-username", "deployment-bearer-token", "deployment-cert-file")
 }
@@ -39,7 +39,13 @@ func main() {
 func getConnector(ctx context.Context, cfg *config) (types.ConnectorServer, error) {
 	l := ctxzap.Extract(ctx)
 
-	cb, err := connector.New(ctx, cfg.DeploymentApiKey, cfg.DeploymentEndpoint, cfg.ApiKey, cfg.OrganizationID)
+	deployments, err := loadDeploymentsFile(cfg.DeploymentsFile)
+	if err != nil {
+		l.Error("error loading deployments file", zap.Error(err))
+		return nil, err
+	}
+
+	cb, err := connector.New(ctx, cfg.ApiKey, cfg.CloudApiURL, cfg.OrganizationID, cfg.ECE, cfg.UseCloudProxy, append(endpointDeployment(cfg), deployments...))
 	if err != nil {
 		l.Error("error creating connector", zap.Error(err))
 		return nil, err
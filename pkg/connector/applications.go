@@ -0,0 +1,200 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/conductorone/baton-elastic/pkg/elastic"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	ent "github.com/conductorone/baton-sdk/pkg/types/entitlement"
+	grant "github.com/conductorone/baton-sdk/pkg/types/grant"
+	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
+)
+
+// allApplicationPrivileges grants every privilege of the application.
+const allApplicationPrivileges = "*"
+
+type applicationBuilder struct {
+	resourceType *v2.ResourceType
+	deployments  *deploymentClients
+}
+
+func (a *applicationBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
+	return a.resourceType
+}
+
+// Create a new connector resource for an application registered with the privileges API of Elastic deployment.
+func applicationResource(deployment *deploymentClient, application string) (*v2.Resource, error) {
+	ret, err := rs.NewResource(
+		application,
+		applicationResourceType,
+		deployment.resourceID(application),
+		rs.WithParentResourceID(deployment.parentResourceID()),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+// List returns the applications registered with the privileges API of the deployment.
+func (a *applicationBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId, pToken *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	deployment, err := a.deployments.forParent(ctx, parentResourceID)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	if deployment == nil {
+		return nil, "", nil, nil
+	}
+
+	privileges, err := deployment.listApplicationPrivileges(ctx)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	var applications []string
+	for application := range privileges {
+		applications = append(applications, application)
+	}
+	slices.Sort(applications)
+
+	var rv []*v2.Resource
+	for _, application := range applications {
+		ar, err := applicationResource(deployment, application)
+		if err != nil {
+			return nil, "", nil, fmt.Errorf("error creating application resource for application %s: %w", application, err)
+		}
+		rv = append(rv, ar)
+	}
+
+	return rv, "", nil, nil
+}
+
+// Entitlements returns an entitlement for every registered privilege of the application.
+func (a *applicationBuilder) Entitlements(ctx context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	deployment, application, err := a.deployments.forResource(ctx, resource.Id.Resource)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	privileges, err := deployment.listApplicationPrivileges(ctx)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	var rv []*v2.Entitlement
+	for _, privilege := range registeredPrivileges(privileges[application]) {
+		rv = append(rv, ent.NewPermissionEntitlement(
+			resource,
+			privilege,
+			ent.WithGrantableTo(deploymentRoleResourceType),
+			ent.WithDisplayName(fmt.Sprintf("%s %s", resource.DisplayName, privilege)),
+			ent.WithDescription(fmt.Sprintf("%s privilege of the %s application", privilege, resource.DisplayName)),
+		))
+	}
+
+	return rv, "", nil, nil
+}
+
+// Grants returns the application privileges the roles of the deployment hold. The resources a role scopes the
+// privilege to, e.g. Kibana spaces, are recorded in the grant metadata. The grants are expanded to the members of the
+// roles.
+func (a *applicationBuilder) Grants(ctx context.Context, resource *v2.Resource, pToken *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	deployment, application, err := a.deployments.forResource(ctx, resource.Id.Resource)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	privileges, err := deployment.listApplicationPrivileges(ctx)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	registered := registeredPrivileges(privileges[application])
+
+	roles, err := deployment.listRoles(ctx)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	var rv []*v2.Grant
+	for roleName, role := range roles {
+		rr, err := deploymentRoleResource(deployment, roleName)
+		if err != nil {
+			return nil, "", nil, fmt.Errorf("error creating role resource for application %s: %w", resource.Id.Resource, err)
+		}
+
+		scopes := applicationPrivilegeResources(deployment, role, application, registered)
+		for _, privilege := range registered {
+			resources, ok := scopes[privilege]
+			if !ok {
+				continue
+			}
+
+			rv = append(rv, grant.NewGrant(
+				resource,
+				privilege,
+				rr.Id,
+				grant.WithGrantMetadata(map[string]interface{}{"resources": resources}),
+				withMembershipExpansion(rr),
+			))
+		}
+	}
+
+	return rv, "", nil, nil
+}
+
+// applicationPrivilegeResources returns the resources the role of the deployment holds each registered privilege of the
+// application on. Privileges given as actions rather than privilege names are not registered and are skipped.
+func applicationPrivilegeResources(deployment *deploymentClient, role elastic.DeploymentRole, application string, registered []string) map[string][]interface{} {
+	rv := make(map[string][]interface{})
+	for _, app := range role.Applications {
+		if !deployment.matchesPattern(app.Application, application) {
+			continue
+		}
+
+		for _, privilege := range app.Privileges {
+			names := []string{privilege}
+			if privilege == allApplicationPrivileges {
+				names = registered
+			}
+
+			for _, name := range names {
+				if !slices.Contains(registered, name) {
+					continue
+				}
+				if _, ok := rv[name]; !ok {
+					rv[name] = []interface{}{}
+				}
+				for _, resource := range app.Resources {
+					if !slices.Contains(rv[name], interface{}(resource)) {
+						rv[name] = append(rv[name], resource)
+					}
+				}
+			}
+		}
+	}
+
+	return rv
+}
+
+// registeredPrivileges returns the sorted names of the registered privileges of an application.
+func registeredPrivileges(privileges map[string]elastic.ApplicationPrivilege) []string {
+	var rv []string
+	for name := range privileges {
+		rv = append(rv, name)
+	}
+	slices.Sort(rv)
+
+	return rv
+}
+
+func newApplicationBuilder(deployments *deploymentClients) *applicationBuilder {
+	return &applicationBuilder{
+		resourceType: applicationResourceType,
+		deployments:  deployments,
+	}
+}
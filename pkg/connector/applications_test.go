@@ -0,0 +1,40 @@
+package connector
+
+import (
+	"testing"
+
+	"github.com/conductorone/baton-elastic/pkg/elastic"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplicationPrivilegeResources(t *testing.T) {
+	deployment := &deploymentClient{id: "d1"}
+	registered := []string{"feature_discover.read", "space_all", "space_read"}
+	role := elastic.DeploymentRole{
+		Applications: []elastic.Applications{
+			{Application: "kibana-.kibana", Privileges: []string{"space_read", "feature_discover.read"}, Resources: []string{"space:default"}},
+			{Application: "kibana-*", Privileges: []string{"space_read"}, Resources: []string{"space:marketing", "space:default"}},
+			{Application: "kibana-.kibana", Privileges: []string{"ui:discover/show", "space_unknown"}, Resources: []string{"*"}},
+			{Application: "apm", Privileges: []string{"space_all"}, Resources: []string{"*"}},
+		},
+	}
+
+	// Privileges given as actions and unregistered privileges are skipped.
+	assert.Equal(t, map[string][]interface{}{
+		"space_read":            {"space:default", "space:marketing"},
+		"feature_discover.read": {"space:default"},
+	}, applicationPrivilegeResources(deployment, role, "kibana-.kibana", registered))
+
+	// The wildcard privilege grants every registered privilege of the application on its resources.
+	role.Applications = []elastic.Applications{
+		{Application: "kibana-.kibana", Privileges: []string{"*"}, Resources: []string{"space:ops"}},
+		{Application: "kibana-.kibana", Privileges: []string{"space_read"}, Resources: []string{"space:default"}},
+	}
+	assert.Equal(t, map[string][]interface{}{
+		"feature_discover.read": {"space:ops"},
+		"space_all":             {"space:ops"},
+		"space_read":            {"space:ops", "space:default"},
+	}, applicationPrivilegeResources(deployment, role, "kibana-.kibana", registered))
+
+	assert.Empty(t, applicationPrivilegeResources(deployment, role, "other", registered))
+}
@@ -0,0 +1,219 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/conductorone/baton-elastic/pkg/elastic"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	ent "github.com/conductorone/baton-sdk/pkg/types/entitlement"
+	grant "github.com/conductorone/baton-sdk/pkg/types/grant"
+	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+)
+
+const apiKeyOwner = "owner"
+
+type cloudAPIKeyBuilder struct {
+	resourceType *v2.ResourceType
+	client       *elastic.Client
+}
+
+func (c *cloudAPIKeyBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
+	return c.resourceType
+}
+
+// Create a new connector resource for Elastic Cloud API key. The key holds the Elastic Cloud roles it was created
+// with, so it is the principal of their grants on the organization, deployments and projects.
+func cloudAPIKeyResource(key *elastic.CloudAPIKey, parentResourceID *v2.ResourceId) (*v2.Resource, error) {
+	profile := map[string]interface{}{
+		"key_id":          key.ID,
+		"description":     key.Description,
+		"user_id":         key.UserID,
+		"org_id":          key.OrganizationID,
+		"creation_date":   key.CreationDate,
+		"expiration_date": key.ExpirationDate,
+	}
+
+	displayName := key.Description
+	if displayName == "" {
+		displayName = key.ID
+	}
+
+	ret, err := rs.NewAppResource(
+		displayName,
+		cloudAPIKeyResourceType,
+		key.ID,
+		[]rs.AppTraitOption{rs.WithAppProfile(profile)},
+		rs.WithParentResourceID(parentResourceID),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+// List returns the Elastic Cloud API keys of the organization members.
+func (c *cloudAPIKeyBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId, pToken *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	if parentResourceID == nil || parentResourceID.ResourceType != organizationResourceType.Id {
+		return nil, "", nil, nil
+	}
+
+	keys, err := listOrgCloudAPIKeys(ctx, c.client, parentResourceID.Resource)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	var rv []*v2.Resource
+	for _, key := range keys {
+		keyCopy := key
+		kr, err := cloudAPIKeyResource(&keyCopy, parentResourceID)
+		if err != nil {
+			return nil, "", nil, fmt.Errorf("error creating API key resource: %w", err)
+		}
+		rv = append(rv, kr)
+	}
+
+	return rv, "", nil, nil
+}
+
+func (c *cloudAPIKeyBuilder) Entitlements(_ context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	return []*v2.Entitlement{
+		ent.NewAssignmentEntitlement(
+			resource,
+			apiKeyOwner,
+			ent.WithGrantableTo(userResourceType),
+			ent.WithDisplayName(fmt.Sprintf("%s API Key %s", resource.DisplayName, apiKeyOwner)),
+			ent.WithDescription(fmt.Sprintf("Created the %s Elastic Cloud API key", resource.DisplayName)),
+		),
+	}, "", nil, nil
+}
+
+// Grants returns the owner grant of the user who created the key.
+func (c *cloudAPIKeyBuilder) Grants(ctx context.Context, resource *v2.Resource, pToken *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	appTrait, err := rs.GetAppTrait(resource)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	userID, ok := rs.GetProfileStringValue(appTrait.Profile, "user_id")
+	if !ok || userID == "" {
+		return nil, "", nil, nil
+	}
+
+	return []*v2.Grant{
+		grant.NewGrant(resource, apiKeyOwner, &v2.ResourceId{
+			ResourceType: userResourceType.Id,
+			Resource:     userID,
+		}),
+	}, "", nil, nil
+}
+
+func (c *cloudAPIKeyBuilder) Grant(ctx context.Context, principal *v2.Resource, entitlement *v2.Entitlement) (annotations.Annotations, error) {
+	return nil, fmt.Errorf("baton-elastic: API keys can only be created in Elastic Cloud")
+}
+
+// Revoke deletes the API key, its owner cannot be changed.
+func (c *cloudAPIKeyBuilder) Revoke(ctx context.Context, grant *v2.Grant) (annotations.Annotations, error) {
+	return deleteCloudAPIKey(ctx, c.client, grant.Entitlement.Resource)
+}
+
+// listOrgCloudAPIKeys returns the Elastic Cloud API keys of the organization. Listing the keys of all members
+// requires the organization admin role, without it no keys are returned so that the grants of the members are
+// still synced.
+func listOrgCloudAPIKeys(ctx context.Context, client *elastic.Client, orgID string) ([]elastic.CloudAPIKey, error) {
+	l := ctxzap.Extract(ctx)
+
+	keys, err := client.ListCloudAPIKeys(ctx)
+	if err != nil {
+		if elastic.IsForbidden(err) {
+			l.Warn(
+				"baton-elastic: skipping API keys of the organization",
+				zap.String("organization_id", orgID),
+				zap.Error(err),
+			)
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error listing API keys: %w", err)
+	}
+
+	var rv []elastic.CloudAPIKey
+	for _, key := range keys {
+		if inOrganization(key.OrganizationID, orgID) {
+			rv = append(rv, key)
+		}
+	}
+
+	return rv, nil
+}
+
+// cloudAPIKeyID returns the ID of the API key resource, the principal of the grants of the roles the key holds.
+func cloudAPIKeyID(key *elastic.CloudAPIKey) *v2.ResourceId {
+	return &v2.ResourceId{
+		ResourceType: cloudAPIKeyResourceType.Id,
+		Resource:     key.ID,
+	}
+}
+
+// deleteCloudAPIKey deletes an Elastic Cloud API key. The roles of a key cannot be changed after it is created, so
+// revoking any grant of the key deletes it. The key is deleted on behalf of the user from the profile of the key
+// resource; the keys of the organization are only listed to find the user when the resource has no profile.
+func deleteCloudAPIKey(ctx context.Context, client *elastic.Client, key *v2.Resource) (annotations.Annotations, error) {
+	l := ctxzap.Extract(ctx)
+	keyID := key.Id.Resource
+
+	userID, err := cloudAPIKeyUserID(ctx, client, key)
+	if err != nil {
+		return nil, err
+	}
+
+	if userID != "" {
+		err := client.DeleteCloudAPIKey(ctx, userID, keyID)
+		if err == nil {
+			return nil, nil
+		}
+		if !elastic.IsNotFound(err) {
+			return nil, fmt.Errorf("baton-elastic: failed to delete API key: %w", err)
+		}
+	}
+
+	l.Warn(
+		"baton-elastic: API key does not exist",
+		zap.String("key_id", keyID),
+	)
+
+	return nil, nil
+}
+
+// cloudAPIKeyUserID returns the ID of the user who created the API key, or an empty string if the key doesn't exist.
+func cloudAPIKeyUserID(ctx context.Context, client *elastic.Client, key *v2.Resource) (string, error) {
+	if appTrait, err := rs.GetAppTrait(key); err == nil {
+		if userID, ok := rs.GetProfileStringValue(appTrait.Profile, "user_id"); ok && userID != "" {
+			return userID, nil
+		}
+	}
+
+	keys, err := client.ListCloudAPIKeys(ctx)
+	if err != nil {
+		return "", fmt.Errorf("error listing API keys: %w", err)
+	}
+
+	for _, k := range keys {
+		if k.ID == key.Id.Resource {
+			return k.UserID, nil
+		}
+	}
+
+	return "", nil
+}
+
+func newCloudAPIKeyBuilder(client *elastic.Client) *cloudAPIKeyBuilder {
+	return &cloudAPIKeyBuilder{
+		resourceType: cloudAPIKeyResourceType,
+		client:       client,
+	}
+}
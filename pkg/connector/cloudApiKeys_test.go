@@ -0,0 +1,73 @@
+package connector
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/conductorone/baton-elastic/pkg/elastic"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCloudAPIKeyRevoke(t *testing.T) {
+	server := newTestServer(t, map[string]testResponse{
+		"GET /api/v1/users/auth/keys/_all": {http.StatusOK, `{"keys":[
+			{"id":"k1","user_id":"u1","organization_id":"org1"},
+			{"id":"k2","user_id":"u2","organization_id":"org1"}
+		]}`},
+		"DELETE /api/v1/users/u1/auth/keys/k1": {http.StatusOK, `{}`},
+		"DELETE /api/v1/users/u2/auth/keys/k2": {http.StatusOK, `{}`},
+	})
+
+	ctx := context.Background()
+	client := elastic.NewClient(server.Client(), "", "", "key", "").WithBaseURL(server.URL)
+	keys := newCloudAPIKeyBuilder(client)
+
+	organization, err := organizationResource(elastic.Organization{ID: "org1", Name: "Org"})
+	assert.Nil(t, err)
+	user, err := userResource(&elastic.User{UserID: "u1"}, organization.Id)
+	assert.Nil(t, err)
+
+	// The key is deleted on behalf of the user from its profile.
+	key, err := cloudAPIKeyResource(&elastic.CloudAPIKey{ID: "k1", UserID: "u1", OrganizationID: "org1"}, organization.Id)
+	assert.Nil(t, err)
+	_, err = keys.Revoke(ctx, &v2.Grant{Entitlement: resourceEntitlement(t, keys, key, apiKeyOwner), Principal: user})
+	assert.Nil(t, err)
+
+	// Without a profile the user is found by listing the keys.
+	_, err = keys.Revoke(ctx, &v2.Grant{
+		Entitlement: &v2.Entitlement{Resource: &v2.Resource{Id: &v2.ResourceId{ResourceType: cloudAPIKeyResourceType.Id, Resource: "k2"}}},
+		Principal:   user,
+	})
+	assert.Nil(t, err)
+
+	// Keys that were already deleted are reported as revoked.
+	_, err = keys.Revoke(ctx, &v2.Grant{
+		Entitlement: &v2.Entitlement{Resource: &v2.Resource{Id: &v2.ResourceId{ResourceType: cloudAPIKeyResourceType.Id, Resource: "k3"}}},
+		Principal:   user,
+	})
+	assert.Nil(t, err)
+
+	assert.Equal(t, []testRequest{
+		{method: http.MethodDelete, path: "/api/v1/users/u1/auth/keys/k1"},
+		{method: http.MethodDelete, path: "/api/v1/users/u2/auth/keys/k2"},
+	}, server.changes())
+}
+
+func TestListOrgCloudAPIKeysForbidden(t *testing.T) {
+	server := newTestServer(t, map[string]testResponse{
+		"GET /api/v1/users/auth/keys/_all": {http.StatusForbidden, `{"errors":[{"code":"root.unauthorized.rbac"}]}`},
+	})
+
+	client := elastic.NewClient(server.Client(), "", "", "key", "").WithBaseURL(server.URL)
+	keys, err := listOrgCloudAPIKeys(context.Background(), client, "org1")
+	assert.Nil(t, err)
+	assert.Empty(t, keys)
+
+	// Revoking needs the keys of the other members, so it fails rather than skipping.
+	_, err = newCloudAPIKeyBuilder(client).Revoke(context.Background(), &v2.Grant{
+		Entitlement: &v2.Entitlement{Resource: &v2.Resource{Id: &v2.ResourceId{ResourceType: cloudAPIKeyResourceType.Id, Resource: "k1"}}},
+	})
+	assert.NotNil(t, err)
+}
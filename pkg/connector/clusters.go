@@ -0,0 +1,141 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	ent "github.com/conductorone/baton-sdk/pkg/types/entitlement"
+	grant "github.com/conductorone/baton-sdk/pkg/types/grant"
+	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
+)
+
+// clusterName is the name of the single cluster resource of a deployment.
+const clusterName = "cluster"
+
+type clusterBuilder struct {
+	resourceType *v2.ResourceType
+	deployments  *deploymentClients
+}
+
+func (c *clusterBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
+	return c.resourceType
+}
+
+// Create a new connector resource for the elasticsearch cluster of Elastic deployment, which cluster privileges
+// are granted on.
+func clusterResource(deployment *deploymentClient) (*v2.Resource, error) {
+	displayName := deployment.name
+	if displayName == "" {
+		displayName = deployment.id
+	}
+
+	ret, err := rs.NewResource(
+		fmt.Sprintf("%s Cluster", displayName),
+		clusterResourceType,
+		deployment.resourceID(clusterName),
+		rs.WithParentResourceID(deployment.parentResourceID()),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+// List returns the cluster of the deployment.
+func (c *clusterBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId, pToken *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	deployment, err := c.deployments.forParent(ctx, parentResourceID)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	if deployment == nil {
+		return nil, "", nil, nil
+	}
+
+	cr, err := clusterResource(deployment)
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("error creating cluster resource for deployment %s: %w", deployment.id, err)
+	}
+
+	return []*v2.Resource{cr}, "", nil, nil
+}
+
+// Entitlements returns an entitlement for every cluster privilege the roles of the deployment hold.
+func (c *clusterBuilder) Entitlements(ctx context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	deployment, _, err := c.deployments.forResource(ctx, resource.Id.Resource)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	roles, err := deployment.listRoles(ctx)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	var privileges []string
+	for _, role := range roles {
+		for _, privilege := range role.Cluster {
+			if !slices.Contains(privileges, privilege) {
+				privileges = append(privileges, privilege)
+			}
+		}
+	}
+	slices.Sort(privileges)
+
+	var rv []*v2.Entitlement
+	for _, privilege := range privileges {
+		rv = append(rv, ent.NewPermissionEntitlement(
+			resource,
+			privilege,
+			ent.WithGrantableTo(deploymentRoleResourceType),
+			ent.WithDisplayName(fmt.Sprintf("%s %s", resource.DisplayName, privilege)),
+			ent.WithDescription(fmt.Sprintf("%s cluster privilege on %s", privilege, resource.DisplayName)),
+		))
+	}
+
+	return rv, "", nil, nil
+}
+
+// Grants returns the cluster privileges the roles of the deployment hold. The grants are expanded to the members of
+// the roles.
+func (c *clusterBuilder) Grants(ctx context.Context, resource *v2.Resource, pToken *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	deployment, _, err := c.deployments.forResource(ctx, resource.Id.Resource)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	roles, err := deployment.listRoles(ctx)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	var rv []*v2.Grant
+	for roleName, role := range roles {
+		rr, err := deploymentRoleResource(deployment, roleName)
+		if err != nil {
+			return nil, "", nil, fmt.Errorf("error creating role resource for cluster %s: %w", resource.Id.Resource, err)
+		}
+
+		var granted []string
+		for _, privilege := range role.Cluster {
+			if slices.Contains(granted, privilege) {
+				continue
+			}
+			granted = append(granted, privilege)
+			rv = append(rv, grant.NewGrant(resource, privilege, rr.Id, withMembershipExpansion(rr)))
+		}
+	}
+
+	return rv, "", nil, nil
+}
+
+func newClusterBuilder(deployments *deploymentClients) *clusterBuilder {
+	return &clusterBuilder{
+		resourceType: clusterResourceType,
+		deployments:  deployments,
+	}
+}
@@ -13,18 +13,39 @@ import (
 )
 
 type Connector struct {
-	client               *elastic.Client
-	shouldSyncDeployment bool
+	client        *elastic.Client
+	deployments   *deploymentClients
+	organizations *organizationCache
+	// syncCloud is set when an Elastic cloud API key is configured. Without it only the configured deployments
+	// are synced.
+	syncCloud bool
+	// ece is set when the cloud API is an Elastic Cloud Enterprise installation, which has platform users instead of
+	// organizations.
+	ece         bool
+	cloudAPIURL string
 }
 
 // ResourceSyncers returns a ResourceSyncer for each resource type that should be synced from the upstream service.
 func (d *Connector) ResourceSyncers(ctx context.Context) []connectorbuilder.ResourceSyncer {
 	return []connectorbuilder.ResourceSyncer{
-		newOrganizationBuilder(d.client),
+		newOrganizationBuilder(d.client, d.syncCloud && !d.ece),
 		newUserBuilder(d.client),
-		newDeploymentRoleBuilder(d.client, d.shouldSyncDeployment),
-		newDeploymentUserBuilder(d.client, d.shouldSyncDeployment),
-		newRoleMappingBuilder(d.client, d.shouldSyncDeployment),
+		newInvitationBuilder(d.client),
+		newCloudAPIKeyBuilder(d.client),
+		newPlatformBuilder(d.client, d.cloudAPIURL, d.syncCloud && d.ece),
+		newPlatformUserBuilder(d.client),
+		newDeploymentBuilder(d.client, d.deployments, d.organizations),
+		newProjectBuilder(d.client, d.organizations),
+		newDeploymentRoleBuilder(d.deployments),
+		newDeploymentUserBuilder(d.deployments),
+		newRoleMappingBuilder(d.deployments),
+		newExternalGroupBuilder(d.deployments),
+		newDeploymentAPIKeyBuilder(d.deployments),
+		newServiceAccountBuilder(d.deployments),
+		newServiceTokenBuilder(d.deployments),
+		newClusterBuilder(d.deployments),
+		newIndexPatternBuilder(d.deployments),
+		newApplicationBuilder(d.deployments),
 	}
 }
 
@@ -39,35 +60,79 @@ func (d *Connector) Metadata(ctx context.Context) (*v2.ConnectorMetadata, error)
 // Validate is called to ensure that the connector is properly configured. It should exercise any API credentials
 // to be sure that they are valid.
 func (d *Connector) Validate(ctx context.Context) (annotations.Annotations, error) {
-	_, err := d.client.ListOrganizations(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("error validating elastic cloud credentials: %w", err)
+	// Validate runs at the start of every sync, so the data cached during the previous sync is dropped here.
+	d.deployments.resetCache()
+	d.organizations.reset()
+
+	switch {
+	case d.syncCloud && d.ece:
+		_, err := d.client.ListPlatformUsers(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("error validating elastic cloud enterprise credentials: %w", err)
+		}
+	case d.syncCloud:
+		_, err := d.client.ListOrganizations(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("error validating elastic cloud credentials: %w", err)
+		}
 	}
 
-	if d.shouldSyncDeployment {
-		err := d.client.DeploymentAuth(ctx)
+	if d.deployments.useCloudProxy {
+		_, err := d.client.ListDeployments(ctx)
 		if err != nil {
-			return nil, fmt.Errorf("error validating elasticsearch deployment credentials: %w", err)
+			return nil, fmt.Errorf("error validating elastic cloud deployment access: %w", err)
+		}
+	}
+
+	for _, deployment := range d.deployments.configured {
+		err := deployment.client.DeploymentAuth(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("error validating elasticsearch deployment %s credentials: %w", deployment.id, err)
 		}
 	}
 
 	return nil, nil
 }
 
-// New returns a new instance of the connector.
-func New(ctx context.Context, deploymentApiKey, deploymentEndpoint, apiKey, organizationID string) (*Connector, error) {
+// New returns a new instance of the connector. With useCloudProxy set, users and roles of every deployment are synced
+// through the Elastic Cloud API proxy. The configured deployments are synced with their own endpoints and credentials.
+// Without an Elastic cloud API key only the configured deployments are synced. With ece set, the cloud API URL points
+// to an Elastic Cloud Enterprise installation whose platform users are synced instead of organizations.
+func New(
+	ctx context.Context,
+	apiKey, cloudAPIURL, organizationID string,
+	ece, useCloudProxy bool,
+	deployments []DeploymentConfig,
+) (*Connector, error) {
 	httpClient, err := uhttp.NewClient(ctx, uhttp.WithLogger(true, ctxzap.Extract(ctx)))
 	if err != nil {
 		return nil, err
 	}
 
-	shouldSyncDeployment := false
-	if deploymentEndpoint != "" {
-		shouldSyncDeployment = true
+	if apiKey == "" && (useCloudProxy || len(deployments) == 0) {
+		return nil, fmt.Errorf("baton-elastic: an Elastic cloud API key is required unless only deployments are configured")
+	}
+
+	configured, err := newConfiguredDeploymentClients(ctx, httpClient, deployments)
+	if err != nil {
+		return nil, fmt.Errorf("baton-elastic: invalid deployment configuration: %w", err)
+	}
+
+	if cloudAPIURL == "" {
+		cloudAPIURL = elastic.DefaultBaseURL
 	}
+	if ece && cloudAPIURL == elastic.DefaultBaseURL {
+		return nil, fmt.Errorf("baton-elastic: the API URL of the Elastic Cloud Enterprise installation is required")
+	}
+
+	client := elastic.NewClient(httpClient, "", "", apiKey, organizationID).WithBaseURL(cloudAPIURL)
 
 	return &Connector{
-		client:               elastic.NewClient(httpClient, deploymentApiKey, deploymentEndpoint, apiKey, organizationID),
-		shouldSyncDeployment: shouldSyncDeployment,
+		client:        client,
+		deployments:   newDeploymentClients(client, useCloudProxy, configured),
+		organizations: newOrganizationCache(client),
+		syncCloud:     apiKey != "",
+		ece:           ece,
+		cloudAPIURL:   cloudAPIURL,
 	}, nil
 }
@@ -0,0 +1,196 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/conductorone/baton-elastic/pkg/elastic"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	ent "github.com/conductorone/baton-sdk/pkg/types/entitlement"
+	grant "github.com/conductorone/baton-sdk/pkg/types/grant"
+	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+)
+
+type deploymentAPIKeyBuilder struct {
+	resourceType *v2.ResourceType
+	deployments  *deploymentClients
+}
+
+func (d *deploymentAPIKeyBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
+	return d.resourceType
+}
+
+// Create a new connector resource for Elastic deployment API key.
+func deploymentAPIKeyResource(deployment *deploymentClient, key *elastic.DeploymentAPIKey) (*v2.Resource, error) {
+	profile := map[string]interface{}{
+		"key_id":      key.ID,
+		"key_name":    key.Name,
+		"type":        key.Type,
+		"username":    key.Username,
+		"realm":       key.Realm,
+		"realm_type":  key.RealmType,
+		"invalidated": key.Invalidated,
+		"creation":    formatMillis(key.Creation),
+	}
+	if key.Expiration != 0 {
+		profile["expiration"] = formatMillis(key.Expiration)
+	}
+
+	displayName := key.Name
+	if displayName == "" {
+		displayName = key.ID
+	}
+
+	ret, err := rs.NewAppResource(
+		displayName,
+		deploymentAPIKeyResourceType,
+		deployment.resourceID(key.ID),
+		[]rs.AppTraitOption{rs.WithAppProfile(profile)},
+		rs.WithParentResourceID(deployment.parentResourceID()),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+// List returns all the API keys of the deployment, including invalidated keys.
+func (d *deploymentAPIKeyBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId, pToken *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	deployment, err := d.deployments.forParent(ctx, parentResourceID)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	if deployment == nil {
+		return nil, "", nil, nil
+	}
+
+	keys, err := deployment.client.ListDeploymentAPIKeys(ctx)
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("error listing deployment API keys: %w", err)
+	}
+
+	var rv []*v2.Resource
+	for _, key := range keys {
+		keyCopy := key
+		kr, err := deploymentAPIKeyResource(deployment, &keyCopy)
+		if err != nil {
+			return nil, "", nil, fmt.Errorf("error creating API key resource for deployment API key %s: %w", key.ID, err)
+		}
+		rv = append(rv, kr)
+	}
+
+	return rv, "", nil, nil
+}
+
+func (d *deploymentAPIKeyBuilder) Entitlements(_ context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	return []*v2.Entitlement{
+		ent.NewAssignmentEntitlement(
+			resource,
+			apiKeyOwner,
+			ent.WithGrantableTo(deploymentUserResourceType),
+			ent.WithDisplayName(fmt.Sprintf("%s API Key %s", resource.DisplayName, apiKeyOwner)),
+			ent.WithDescription(fmt.Sprintf("Owner of the %s elasticsearch API key", resource.DisplayName)),
+		),
+	}, "", nil, nil
+}
+
+// Grants returns the owner grant of the deployment user the key belongs to. Keys of users from other realms, e.g.
+// SAML or LDAP, have no deployment user resource to be granted to. Invalidated and expired keys grant no access, so
+// they have no owner grant either.
+func (d *deploymentAPIKeyBuilder) Grants(ctx context.Context, resource *v2.Resource, pToken *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	appTrait, err := rs.GetAppTrait(resource)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	if appTrait.Profile.GetFields()["invalidated"].GetBoolValue() {
+		return nil, "", nil, nil
+	}
+	if expiration, ok := rs.GetProfileStringValue(appTrait.Profile, "expiration"); ok {
+		expiresAt, err := time.Parse(time.RFC3339, expiration)
+		if err != nil {
+			return nil, "", nil, fmt.Errorf("baton-elastic: invalid expiration of API key %s: %w", resource.Id.Resource, err)
+		}
+		if !expiresAt.After(time.Now()) {
+			return nil, "", nil, nil
+		}
+	}
+
+	username, _ := rs.GetProfileStringValue(appTrait.Profile, "username")
+	realm, _ := rs.GetProfileStringValue(appTrait.Profile, "realm")
+	realmType, _ := rs.GetProfileStringValue(appTrait.Profile, "realm_type")
+	if username == "" || !isDeploymentUserRealm(realm, realmType) {
+		return nil, "", nil, nil
+	}
+
+	deployment, _, err := d.deployments.forResource(ctx, resource.Id.Resource)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	return []*v2.Grant{
+		grant.NewGrant(resource, apiKeyOwner, &v2.ResourceId{
+			ResourceType: deploymentUserResourceType.Id,
+			Resource:     deployment.resourceID(username),
+		}),
+	}, "", nil, nil
+}
+
+func (d *deploymentAPIKeyBuilder) Grant(ctx context.Context, principal *v2.Resource, entitlement *v2.Entitlement) (annotations.Annotations, error) {
+	return nil, fmt.Errorf("baton-elastic: API keys can only be created by their owner")
+}
+
+// Revoke invalidates the API key, its owner cannot be changed.
+func (d *deploymentAPIKeyBuilder) Revoke(ctx context.Context, grant *v2.Grant) (annotations.Annotations, error) {
+	l := ctxzap.Extract(ctx)
+
+	deployment, keyID, err := d.deployments.forResource(ctx, grant.Entitlement.Resource.Id.Resource)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := deployment.client.InvalidateDeploymentAPIKey(ctx, keyID)
+	if err != nil {
+		return nil, fmt.Errorf("baton-elastic: failed to invalidate API key: %w", err)
+	}
+
+	if len(res.InvalidatedAPIKeys) == 0 {
+		l.Warn(
+			"baton-elastic: API key is already invalidated",
+			zap.String("key_id", grant.Entitlement.Resource.Id.Resource),
+		)
+	}
+
+	return nil, nil
+}
+
+// isDeploymentUserRealm reports whether users of the realm are listed as deployment users, which are the users of
+// the native and reserved realms. Older elasticsearch versions report only the realm name.
+func isDeploymentUserRealm(realm, realmType string) bool {
+	switch realmType {
+	case "native", "reserved":
+		return true
+	case "":
+		return realm == "default_native" || realm == "reserved"
+	default:
+		return false
+	}
+}
+
+// formatMillis formats milliseconds since the epoch as RFC 3339.
+func formatMillis(millis int64) string {
+	return time.UnixMilli(millis).UTC().Format(time.RFC3339)
+}
+
+func newDeploymentAPIKeyBuilder(deployments *deploymentClients) *deploymentAPIKeyBuilder {
+	return &deploymentAPIKeyBuilder{
+		resourceType: deploymentAPIKeyResourceType,
+		deployments:  deployments,
+	}
+}
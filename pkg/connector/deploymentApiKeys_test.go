@@ -0,0 +1,62 @@
+package connector
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/conductorone/baton-elastic/pkg/elastic"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeploymentAPIKeyRevoke(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+		wantErr  bool
+	}{
+		{
+			name:     "invalidated",
+			response: `{"invalidated_api_keys":["k1"],"previously_invalidated_api_keys":[],"error_count":0}`,
+		},
+		{
+			name:     "previously invalidated",
+			response: `{"invalidated_api_keys":[],"previously_invalidated_api_keys":["k1"],"error_count":0}`,
+		},
+		{
+			name:     "errors",
+			response: `{"invalidated_api_keys":[],"previously_invalidated_api_keys":[],"error_count":1,"error_details":[{"type":"exception"}]}`,
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newTestServer(t, map[string]testResponse{
+				"DELETE /_security/api_key": {http.StatusOK, tt.response},
+			})
+
+			deployment := &deploymentClient{
+				id:     "d1",
+				client: elastic.NewDeploymentClient(server.Client(), server.URL, elastic.DeploymentCredentials{APIKey: "key"}),
+			}
+			keys := newDeploymentAPIKeyBuilder(newDeploymentClients(nil, false, []*deploymentClient{deployment}))
+
+			key, err := deploymentAPIKeyResource(deployment, &elastic.DeploymentAPIKey{ID: "k1", Username: "alice", Realm: "default_native"})
+			assert.Nil(t, err)
+
+			_, err = keys.Revoke(context.Background(), &v2.Grant{Entitlement: resourceEntitlement(t, keys, key, apiKeyOwner)})
+			if tt.wantErr {
+				assert.NotNil(t, err)
+			} else {
+				assert.Nil(t, err)
+			}
+
+			// Only the key is invalidated by its ID, not every key of its owner by username and realm.
+			assert.Equal(t, []testRequest{
+				{method: http.MethodDelete, path: "/_security/api_key", body: `{"ids":["k1"]}`},
+			}, server.changes())
+		})
+	}
+}
@@ -0,0 +1,254 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/conductorone/baton-elastic/pkg/elastic"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/uhttp"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+)
+
+// deploymentIDSeparator separates the deployment ID from the name of deployment scoped resources.
+const deploymentIDSeparator = "/"
+
+// deploymentClient is the client of a single elasticsearch deployment.
+type deploymentClient struct {
+	id             string
+	name           string
+	endpoint       string
+	kibanaEndpoint string
+	client         *elastic.Client
+
+	// mu guards roles, privileges and patterns, the roles and application privileges of the deployment and the
+	// compiled name patterns of its roles cached for the current sync.
+	mu         sync.Mutex
+	roles      map[string]elastic.DeploymentRole
+	privileges map[string]map[string]elastic.ApplicationPrivilege
+	patterns   map[string]*regexp.Regexp
+}
+
+// resourceID returns the ID of a resource of the deployment.
+func (d *deploymentClient) resourceID(name string) string {
+	return d.id + deploymentIDSeparator + name
+}
+
+// parentResourceID returns the ID of the deployment resource the resources of the deployment are listed under.
+func (d *deploymentClient) parentResourceID() *v2.ResourceId {
+	return &v2.ResourceId{
+		ResourceType: deploymentResourceType.Id,
+		Resource:     d.id,
+	}
+}
+
+// listRoles returns the roles of the deployment. The roles are fetched once per sync, as the roles, index patterns,
+// cluster privileges and applications of the deployment are all synced from them.
+func (d *deploymentClient) listRoles(ctx context.Context) (map[string]elastic.DeploymentRole, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.roles != nil {
+		return d.roles, nil
+	}
+
+	roles, err := d.client.ListDeploymentRoles(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error listing roles: %w", err)
+	}
+	d.roles = roles
+
+	return roles, nil
+}
+
+// listApplicationPrivileges returns the privileges registered for the applications of the deployment, by application.
+// The privileges are fetched once per sync, like the roles.
+func (d *deploymentClient) listApplicationPrivileges(ctx context.Context) (map[string]map[string]elastic.ApplicationPrivilege, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.privileges != nil {
+		return d.privileges, nil
+	}
+
+	privileges, err := d.client.ListApplicationPrivileges(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error listing application privileges: %w", err)
+	}
+	d.privileges = privileges
+
+	return privileges, nil
+}
+
+// matchesPattern reports whether the value matches an elasticsearch name pattern of the deployment, e.g. a run_as
+// pattern or application name of a role. Patterns are compiled once per sync, patterns that are not supported match
+// nothing.
+func (d *deploymentClient) matchesPattern(pattern, value string) bool {
+	d.mu.Lock()
+	re, ok := d.patterns[pattern]
+	if !ok {
+		if d.patterns == nil {
+			d.patterns = make(map[string]*regexp.Regexp)
+		}
+		re = compilePattern(pattern)
+		d.patterns[pattern] = re
+	}
+	d.mu.Unlock()
+
+	return re != nil && re.MatchString(value)
+}
+
+// resetCache drops the data cached during the previous sync.
+func (d *deploymentClient) resetCache() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.roles = nil
+	d.privileges = nil
+	d.patterns = nil
+}
+
+// deploymentClients resolves the clients of the deployments whose users, roles and role mappings are synced.
+type deploymentClients struct {
+	client *elastic.Client
+	// useCloudProxy is set when every deployment of the organization is reached through the Elastic Cloud proxy.
+	useCloudProxy bool
+	// configured are the deployment passed with --deployment-endpoint and the deployments listed in the deployments
+	// file, in the order they were configured.
+	configured []*deploymentClient
+
+	mu      sync.Mutex
+	proxied map[string]*deploymentClient
+}
+
+// forParent returns the client of the deployment whose resources are listed under the parent resource,
+// or nil if nothing should be listed there.
+func (d *deploymentClients) forParent(ctx context.Context, parentResourceID *v2.ResourceId) (*deploymentClient, error) {
+	if parentResourceID == nil || parentResourceID.ResourceType != deploymentResourceType.Id {
+		return nil, nil
+	}
+
+	return d.get(ctx, parentResourceID.Resource)
+}
+
+// forResource returns the client of the deployment the resource belongs to, along with the resource name
+// within the deployment.
+func (d *deploymentClients) forResource(ctx context.Context, resourceID string) (*deploymentClient, string, error) {
+	deploymentID, name, ok := strings.Cut(resourceID, deploymentIDSeparator)
+	if !ok {
+		return nil, "", fmt.Errorf("baton-elastic: resource %s is not scoped to a deployment", resourceID)
+	}
+
+	dc, err := d.get(ctx, deploymentID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return dc, name, nil
+}
+
+func (d *deploymentClients) get(ctx context.Context, deploymentID string) (*deploymentClient, error) {
+	for _, dc := range d.configured {
+		if dc.id == deploymentID {
+			return dc, nil
+		}
+	}
+
+	if !d.useCloudProxy {
+		return nil, fmt.Errorf("baton-elastic: deployment %s is not configured", deploymentID)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if dc, ok := d.proxied[deploymentID]; ok {
+		return dc, nil
+	}
+
+	deployment, err := d.client.GetDeployment(ctx, deploymentID)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching deployment %s: %w", deploymentID, err)
+	}
+
+	es := deployment.Elasticsearch()
+	if es == nil {
+		return nil, fmt.Errorf("baton-elastic: deployment %s has no elasticsearch cluster", deploymentID)
+	}
+
+	dc := &deploymentClient{
+		id:     deploymentID,
+		name:   deployment.Name,
+		client: d.client.DeploymentProxy(deploymentID, es.RefID),
+	}
+	d.proxied[deploymentID] = dc
+
+	return dc, nil
+}
+
+// resetCache drops the data cached during the previous sync by the clients of all deployments.
+func (d *deploymentClients) resetCache() {
+	for _, dc := range d.configured {
+		dc.resetCache()
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for _, dc := range d.proxied {
+		dc.resetCache()
+	}
+}
+
+func newDeploymentClients(client *elastic.Client, useCloudProxy bool, configured []*deploymentClient) *deploymentClients {
+	return &deploymentClients{
+		client:        client,
+		useCloudProxy: useCloudProxy,
+		configured:    configured,
+		proxied:       make(map[string]*deploymentClient),
+	}
+}
+
+// newConfiguredDeploymentClients returns clients of the configured deployments, each reached with its own endpoint
+// and credentials. Deployments without TLS settings share the default HTTP client.
+func newConfiguredDeploymentClients(ctx context.Context, httpClient *http.Client, deployments []DeploymentConfig) ([]*deploymentClient, error) {
+	var rv []*deploymentClient
+	seen := make(map[string]bool)
+	for _, deployment := range deployments {
+		if err := deployment.setDefaults(); err != nil {
+			return nil, err
+		}
+		if err := deployment.validate(); err != nil {
+			return nil, err
+		}
+		if seen[deployment.ID] {
+			return nil, fmt.Errorf("deployment %s is configured more than once", deployment.ID)
+		}
+		seen[deployment.ID] = true
+
+		deploymentHTTPClient := httpClient
+		tlsConfig, err := deployment.tlsConfig()
+		if err != nil {
+			return nil, err
+		}
+		if tlsConfig != nil {
+			deploymentHTTPClient, err = uhttp.NewClient(ctx, uhttp.WithLogger(true, ctxzap.Extract(ctx)), uhttp.WithTLSClientConfig(tlsConfig))
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		rv = append(rv, &deploymentClient{
+			id:             deployment.ID,
+			name:           deployment.Name,
+			endpoint:       deployment.Endpoint,
+			kibanaEndpoint: deployment.KibanaEndpoint,
+			client:         elastic.NewDeploymentClient(deploymentHTTPClient, deployment.Endpoint, deployment.credentials()),
+		})
+	}
+
+	return rv, nil
+}
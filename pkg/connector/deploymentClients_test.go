@@ -0,0 +1,39 @@
+package connector
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/conductorone/baton-elastic/pkg/elastic"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeploymentClientListRoles(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		_, _ = w.Write([]byte(`{"viewer":{"indices":[{"names":["logs-*"],"privileges":["read"]}]}}`))
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	deployment := &deploymentClient{
+		id:     "d1",
+		client: elastic.NewDeploymentClient(server.Client(), server.URL, elastic.DeploymentCredentials{APIKey: "key"}),
+	}
+	deployments := newDeploymentClients(nil, false, []*deploymentClient{deployment})
+
+	for i := 0; i < 3; i++ {
+		roles, err := deployment.listRoles(ctx)
+		assert.Nil(t, err)
+		assert.Contains(t, roles, "viewer")
+	}
+	assert.Equal(t, 1, requests)
+
+	deployments.resetCache()
+	_, err := deployment.listRoles(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, requests)
+}
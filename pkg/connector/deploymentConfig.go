@@ -0,0 +1,166 @@
+package connector
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/conductorone/baton-elastic/pkg/elastic"
+)
+
+// DeploymentConfig configures an elasticsearch deployment synced with its own endpoint and credentials. Exactly one
+// of API key, username and password, bearer token or client certificate (PKI) has to be set.
+type DeploymentConfig struct {
+	// ID namespaces the resources of the deployment, it defaults to the host of the endpoint.
+	ID       string `yaml:"id" json:"id"`
+	Name     string `yaml:"name" json:"name"`
+	Endpoint string `yaml:"endpoint" json:"endpoint"`
+	// CloudID is decoded into the endpoint when no endpoint is configured.
+	CloudID string `yaml:"cloud_id" json:"cloud_id"`
+	// KibanaEndpoint is decoded from the Cloud ID.
+	KibanaEndpoint string `yaml:"-" json:"-"`
+
+	APIKey   string `yaml:"api_key" json:"api_key"`
+	Username string `yaml:"username" json:"username"`
+	Password string `yaml:"password" json:"password"`
+	// BearerToken is an access token issued by the _security/oauth2/token API.
+	BearerToken string `yaml:"bearer_token" json:"bearer_token"`
+	// CertFile and KeyFile are the PEM encoded TLS client certificate and key used to authenticate with the PKI realm.
+	CertFile string `yaml:"cert_file" json:"cert_file"`
+	KeyFile  string `yaml:"key_file" json:"key_file"`
+	// CAFile is a PEM encoded CA bundle trusted in addition to the system roots.
+	CAFile string `yaml:"ca_file" json:"ca_file"`
+}
+
+// setDefaults decodes the endpoints from the Cloud ID, and derives the ID from the endpoint host and the name from
+// the Cloud ID or the ID when they are not configured.
+func (d *DeploymentConfig) setDefaults() error {
+	if d.CloudID != "" {
+		if d.Endpoint != "" {
+			return fmt.Errorf("deployment %s can't have both an endpoint and a cloud id", d.label())
+		}
+
+		endpoints, err := elastic.ParseCloudID(d.CloudID)
+		if err != nil {
+			return fmt.Errorf("deployment %s: %w", d.label(), err)
+		}
+		d.Endpoint = endpoints.Elasticsearch
+		d.KibanaEndpoint = endpoints.Kibana
+		if d.Name == "" {
+			d.Name = endpoints.Name
+		}
+	}
+
+	if d.ID == "" {
+		u, err := url.Parse(d.Endpoint)
+		if err != nil {
+			return fmt.Errorf("invalid deployment endpoint: %w", err)
+		}
+		if u.Hostname() == "" {
+			return fmt.Errorf("invalid deployment endpoint %s: host is missing", d.Endpoint)
+		}
+		d.ID = u.Hostname()
+	}
+
+	if d.Name == "" {
+		d.Name = d.ID
+	}
+
+	return nil
+}
+
+// label names the deployment in errors before its ID is derived: by its ID, or else by the label of its Cloud ID, the
+// Cloud ID itself or its endpoint.
+func (d *DeploymentConfig) label() string {
+	if d.ID != "" {
+		return d.ID
+	}
+	if d.CloudID != "" {
+		if name, _, ok := strings.Cut(d.CloudID, ":"); ok && name != "" {
+			return name
+		}
+		return d.CloudID
+	}
+
+	return d.Endpoint
+}
+
+// validate returns an error if the deployment can't be synced.
+func (d *DeploymentConfig) validate() error {
+	if d.ID == "" {
+		return fmt.Errorf("deployment id is missing")
+	}
+	if strings.Contains(d.ID, deploymentIDSeparator) {
+		return fmt.Errorf("deployment id %s must not contain %s", d.ID, deploymentIDSeparator)
+	}
+	if d.Endpoint == "" {
+		return fmt.Errorf("endpoint of deployment %s is missing", d.ID)
+	}
+	if (d.Username == "") != (d.Password == "") {
+		return fmt.Errorf("username and password of deployment %s have to be set together", d.ID)
+	}
+	if (d.CertFile == "") != (d.KeyFile == "") {
+		return fmt.Errorf("certificate and key files of deployment %s have to be set together", d.ID)
+	}
+
+	authMethods := 0
+	for _, set := range []bool{d.APIKey != "", d.Username != "", d.BearerToken != "", d.CertFile != ""} {
+		if set {
+			authMethods++
+		}
+	}
+	if authMethods != 1 {
+		return fmt.Errorf("deployment %s needs exactly one of api key, username and password, bearer token or client certificate", d.ID)
+	}
+
+	return nil
+}
+
+func (d *DeploymentConfig) credentials() elastic.DeploymentCredentials {
+	return elastic.DeploymentCredentials{
+		APIKey:      d.APIKey,
+		Username:    d.Username,
+		Password:    d.Password,
+		BearerToken: d.BearerToken,
+	}
+}
+
+// tlsConfig returns the TLS configuration of the deployment, or nil when the defaults are used.
+func (d *DeploymentConfig) tlsConfig() (*tls.Config, error) {
+	if d.CertFile == "" && d.CAFile == "" {
+		return nil, nil
+	}
+
+	config := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+	}
+
+	if d.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(d.CertFile, d.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("error loading client certificate of deployment %s: %w", d.ID, err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	if d.CAFile != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+
+		ca, err := os.ReadFile(d.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("error reading CA file of deployment %s: %w", d.ID, err)
+		}
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("CA file of deployment %s contains no PEM certificates", d.ID)
+		}
+		config.RootCAs = pool
+	}
+
+	return config, nil
+}
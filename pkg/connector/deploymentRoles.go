@@ -3,8 +3,8 @@ package connector
 import (
 	"context"
 	"fmt"
+	"slices"
 
-	"github.com/conductorone/baton-elastic/pkg/elastic"
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
 	"github.com/conductorone/baton-sdk/pkg/annotations"
 	"github.com/conductorone/baton-sdk/pkg/pagination"
@@ -18,9 +18,8 @@ import (
 const roleMembership = "member"
 
 type roleBuilder struct {
-	resourceType         *v2.ResourceType
-	client               *elastic.Client
-	shouldSyncDeployment bool
+	resourceType *v2.ResourceType
+	deployments  *deploymentClients
 }
 
 func (r *roleBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
@@ -28,7 +27,7 @@ func (r *roleBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
 }
 
 // Create a new connector resource for Elastic deployment role.
-func deploymentRoleResource(role string) (*v2.Resource, error) {
+func deploymentRoleResource(deployment *deploymentClient, role string) (*v2.Resource, error) {
 	profile := map[string]interface{}{
 		"role_name": role,
 		// no ID in api response
@@ -42,8 +41,9 @@ func deploymentRoleResource(role string) (*v2.Resource, error) {
 	ret, err := rs.NewRoleResource(
 		role,
 		deploymentRoleResourceType,
-		role,
+		deployment.resourceID(role),
 		roleOptions,
+		rs.WithParentResourceID(deployment.parentResourceID()),
 	)
 	if err != nil {
 		return nil, err
@@ -55,18 +55,22 @@ func deploymentRoleResource(role string) (*v2.Resource, error) {
 // List returns all the roles from the database as resource objects.
 // Roles include a RoleTrait because they are the 'shape' of a standard role.
 func (r *roleBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId, pToken *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
-	if !r.shouldSyncDeployment {
+	deployment, err := r.deployments.forParent(ctx, parentResourceID)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	if deployment == nil {
 		return nil, "", nil, nil
 	}
 
-	roles, err := r.client.ListDeploymentRoles(ctx)
+	roles, err := deployment.listRoles(ctx)
 	if err != nil {
-		return nil, "", nil, fmt.Errorf("error listing roles: %w", err)
+		return nil, "", nil, err
 	}
 
 	var rv []*v2.Resource
 	for key := range roles {
-		ur, err := deploymentRoleResource(key)
+		ur, err := deploymentRoleResource(deployment, key)
 		if err != nil {
 			return nil, "", nil, fmt.Errorf("error creating role resource for role %s: %w", key, err)
 		}
@@ -79,7 +83,7 @@ func (r *roleBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId,
 func (r *roleBuilder) Entitlements(_ context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
 	var rv []*v2.Entitlement
 	assignmentOptions := []ent.EntitlementOption{
-		ent.WithGrantableTo(deploymentUserResourceType),
+		ent.WithGrantableTo(deploymentUserResourceType, roleMappingResourceType),
 		ent.WithDisplayName(fmt.Sprintf("%s Role %s", resource.DisplayName, roleMembership)),
 		ent.WithDescription(fmt.Sprintf("Member of %s elasticsearch role", resource.DisplayName)),
 	}
@@ -94,7 +98,12 @@ func (r *roleBuilder) Entitlements(_ context.Context, resource *v2.Resource, _ *
 }
 
 func (r *roleBuilder) Grants(ctx context.Context, resource *v2.Resource, pToken *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
-	users, err := r.client.ListDeploymentUsers(ctx)
+	deployment, roleName, err := r.deployments.forResource(ctx, resource.Id.Resource)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	users, err := deployment.client.ListDeploymentUsers(ctx)
 	if err != nil {
 		return nil, "", nil, err
 	}
@@ -102,8 +111,8 @@ func (r *roleBuilder) Grants(ctx context.Context, resource *v2.Resource, pToken
 	var rv []*v2.Grant
 	for _, user := range users {
 		userCopy := user
-		if hasRole(resource.Id.Resource, user.Roles) {
-			ur, err := deploymentUserResource(&userCopy)
+		if hasRole(roleName, user.Roles) {
+			ur, err := deploymentUserResource(deployment, &userCopy)
 			if err != nil {
 				return nil, "", nil, fmt.Errorf("error creating user resource for role %s: %w", resource.Id.Resource, err)
 			}
@@ -112,30 +121,65 @@ func (r *roleBuilder) Grants(ctx context.Context, resource *v2.Resource, pToken
 		}
 	}
 
+	mappings, err := deployment.client.ListDeploymentRoleMapping(ctx)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	for mappingName, mapping := range mappings {
+		if !hasRole(roleName, mapping.Roles) {
+			continue
+		}
+
+		mr, err := roleMappingResource(deployment, mappingName)
+		if err != nil {
+			return nil, "", nil, fmt.Errorf("error creating role mapping resource for role %s: %w", resource.Id.Resource, err)
+		}
+		rv = append(rv, grant.NewGrant(resource, roleMembership, mr.Id, withMembershipExpansion(mr)))
+	}
+
 	return rv, "", nil, nil
 }
 
+// Grant adds the role to the roles of a deployment user, or to the roles a role mapping maps its members to.
 func (r *roleBuilder) Grant(ctx context.Context, principal *v2.Resource, entitlement *v2.Entitlement) (annotations.Annotations, error) {
 	l := ctxzap.Extract(ctx)
 
-	if principal.Id.ResourceType != deploymentUserResourceType.Id {
+	switch principal.Id.ResourceType {
+	case deploymentUserResourceType.Id:
+	case roleMappingResourceType.Id:
+		return r.grantRoleMapping(ctx, principal, entitlement)
+	default:
 		l.Warn(
-			"baton-elastic: only users can be granted role membership",
+			"baton-elastic: only users and role mappings can be granted role membership",
 			zap.String("principal_type", principal.Id.ResourceType),
 			zap.String("principal_id", principal.Id.Resource),
 		)
-		return nil, fmt.Errorf("baton-elastic: only users can be granted role membership")
+		return nil, fmt.Errorf("baton-elastic: only users and role mappings can be granted role membership")
+	}
+
+	deployment, roleName, err := r.deployments.forResource(ctx, entitlement.Resource.Id.Resource)
+	if err != nil {
+		return nil, err
+	}
+
+	userDeployment, username, err := r.deployments.forResource(ctx, principal.Id.Resource)
+	if err != nil {
+		return nil, err
+	}
+	if userDeployment.id != deployment.id {
+		return nil, fmt.Errorf("baton-elastic: user %s and role %s belong to different deployments", principal.Id.Resource, entitlement.Resource.Id.Resource)
 	}
 
-	user, err := r.client.GetDeploymentUser(ctx, principal.Id.Resource)
+	user, err := deployment.client.GetDeploymentUser(ctx, username)
 	if err != nil {
 		return nil, fmt.Errorf("error fetching user: %w", err)
 	}
 
-	newUser := user[principal.Id.Resource]
-	newUser.Roles = append(newUser.Roles, entitlement.Resource.Id.Resource)
+	newUser := user[username]
+	newUser.Roles = append(newUser.Roles, roleName)
 
-	err = r.client.UpdateUser(ctx, principal.Id.Resource, newUser)
+	err = deployment.client.UpdateUser(ctx, username, newUser)
 	if err != nil {
 		return nil, fmt.Errorf("baton-elastic: failed to grant role to user: %w", err)
 	}
@@ -143,35 +187,53 @@ func (r *roleBuilder) Grant(ctx context.Context, principal *v2.Resource, entitle
 	return nil, nil
 }
 
+// Revoke removes the role from the roles of a deployment user, or from the roles a role mapping maps its members to.
 func (r *roleBuilder) Revoke(ctx context.Context, grant *v2.Grant) (annotations.Annotations, error) {
 	l := ctxzap.Extract(ctx)
 	principal := grant.Principal
 	entitlement := grant.Entitlement
 
-	if principal.Id.ResourceType != deploymentUserResourceType.Id {
+	switch principal.Id.ResourceType {
+	case deploymentUserResourceType.Id:
+	case roleMappingResourceType.Id:
+		return r.revokeRoleMapping(ctx, principal, entitlement)
+	default:
 		l.Warn(
-			"baton-elastic: only users can have role membership revoked",
+			"baton-elastic: only users and role mappings can have role membership revoked",
 			zap.String("principal_type", principal.Id.ResourceType),
 			zap.String("principal_id", principal.Id.Resource),
 		)
-		return nil, fmt.Errorf("baton-elastic: only users can have role membership revoked")
+		return nil, fmt.Errorf("baton-elastic: only users and role mappings can have role membership revoked")
+	}
+
+	deployment, roleName, err := r.deployments.forResource(ctx, entitlement.Resource.Id.Resource)
+	if err != nil {
+		return nil, err
+	}
+
+	userDeployment, username, err := r.deployments.forResource(ctx, principal.Id.Resource)
+	if err != nil {
+		return nil, err
+	}
+	if userDeployment.id != deployment.id {
+		return nil, fmt.Errorf("baton-elastic: user %s and role %s belong to different deployments", principal.Id.Resource, entitlement.Resource.Id.Resource)
 	}
 
-	user, err := r.client.GetDeploymentUser(ctx, principal.Id.Resource)
+	user, err := deployment.client.GetDeploymentUser(ctx, username)
 	if err != nil {
 		return nil, fmt.Errorf("error fetching user: %w", err)
 	}
 
 	var roles []string
-	for _, role := range user[principal.Id.Resource].Roles {
-		if role != entitlement.Resource.Id.Resource {
+	for _, role := range user[username].Roles {
+		if role != roleName {
 			roles = append(roles, role)
 		}
 	}
 
-	newUser := user[principal.Id.Resource]
+	newUser := user[username]
 	newUser.Roles = roles
-	err = r.client.UpdateUser(ctx, principal.Id.Resource, newUser)
+	err = deployment.client.UpdateUser(ctx, username, newUser)
 	if err != nil {
 		return nil, fmt.Errorf("baton-elastic: failed to revoke user role: %w", err)
 	}
@@ -179,11 +241,97 @@ func (r *roleBuilder) Revoke(ctx context.Context, grant *v2.Grant) (annotations.
 	return nil, nil
 }
 
-func newDeploymentRoleBuilder(client *elastic.Client, shouldSyncDeployment bool) *roleBuilder {
+// grantRoleMapping adds the role to the roles of the role mapping principal.
+func (r *roleBuilder) grantRoleMapping(ctx context.Context, principal *v2.Resource, entitlement *v2.Entitlement) (annotations.Annotations, error) {
+	l := ctxzap.Extract(ctx)
+
+	deployment, roleName, mappingName, err := r.roleMappingMembership(ctx, principal, entitlement)
+	if err != nil {
+		return nil, err
+	}
+
+	mapping, err := getRoleMapping(ctx, deployment, mappingName)
+	if err != nil {
+		return nil, err
+	}
+
+	if hasRole(roleName, mapping.Roles) {
+		l.Warn(
+			"baton-elastic: role mapping already has this role",
+			zap.String("principal_id", principal.Id.Resource),
+			zap.String("role", roleName),
+		)
+		return nil, nil
+	}
+
+	mapping.Roles = append(mapping.Roles, roleName)
+	if err := deployment.client.PutDeploymentRoleMapping(ctx, mappingName, mapping); err != nil {
+		return nil, fmt.Errorf("baton-elastic: failed to grant role to role mapping: %w", err)
+	}
+
+	return nil, nil
+}
+
+// revokeRoleMapping removes the role from the roles of the role mapping principal. A role mapping must map to at
+// least one role or role template, so the last role of a mapping without role templates can't be removed; the role
+// mapping has to be deleted instead.
+func (r *roleBuilder) revokeRoleMapping(ctx context.Context, principal *v2.Resource, entitlement *v2.Entitlement) (annotations.Annotations, error) {
+	l := ctxzap.Extract(ctx)
+
+	deployment, roleName, mappingName, err := r.roleMappingMembership(ctx, principal, entitlement)
+	if err != nil {
+		return nil, err
+	}
+
+	mapping, err := getRoleMapping(ctx, deployment, mappingName)
+	if err != nil {
+		return nil, err
+	}
+
+	if !hasRole(roleName, mapping.Roles) {
+		l.Warn(
+			"baton-elastic: role mapping does not have this role",
+			zap.String("principal_id", principal.Id.Resource),
+			zap.String("role", roleName),
+		)
+		return nil, nil
+	}
+
+	mapping.Roles = slices.DeleteFunc(mapping.Roles, func(role string) bool { return role == roleName })
+	if len(mapping.Roles) == 0 && len(mapping.RuleTemplate) == 0 {
+		return nil, fmt.Errorf("baton-elastic: %s is the last role of role mapping %s and cannot be removed", roleName, mappingName)
+	}
+
+	if err := deployment.client.PutDeploymentRoleMapping(ctx, mappingName, mapping); err != nil {
+		return nil, fmt.Errorf("baton-elastic: failed to revoke role from role mapping: %w", err)
+	}
+
+	return nil, nil
+}
+
+// roleMappingMembership returns the deployment and the role name of the role entitlement, and the name of the role
+// mapping principal.
+func (r *roleBuilder) roleMappingMembership(ctx context.Context, principal *v2.Resource, entitlement *v2.Entitlement) (*deploymentClient, string, string, error) {
+	deployment, roleName, err := r.deployments.forResource(ctx, entitlement.Resource.Id.Resource)
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	mappingDeployment, mappingName, err := r.deployments.forResource(ctx, principal.Id.Resource)
+	if err != nil {
+		return nil, "", "", err
+	}
+	if mappingDeployment.id != deployment.id {
+		return nil, "", "", fmt.Errorf("baton-elastic: role mapping %s and role %s belong to different deployments", principal.Id.Resource, entitlement.Resource.Id.Resource)
+	}
+
+	return deployment, roleName, mappingName, nil
+}
+
+func newDeploymentRoleBuilder(deployments *deploymentClients) *roleBuilder {
 	return &roleBuilder{
-		resourceType:         deploymentRoleResourceType,
-		client:               client,
-		shouldSyncDeployment: shouldSyncDeployment,
+		resourceType: deploymentRoleResourceType,
+		deployments:  deployments,
 	}
 }
 
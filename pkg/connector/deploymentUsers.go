@@ -9,13 +9,17 @@ import (
 	"github.com/conductorone/baton-sdk/pkg/annotations"
 	"github.com/conductorone/baton-sdk/pkg/helpers"
 	"github.com/conductorone/baton-sdk/pkg/pagination"
+	ent "github.com/conductorone/baton-sdk/pkg/types/entitlement"
+	grant "github.com/conductorone/baton-sdk/pkg/types/grant"
 	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
 )
 
+// userImpersonation is the entitlement of the roles allowed to run as the deployment user.
+const userImpersonation = "impersonate"
+
 type deploymentUserBuilder struct {
-	resourceType         *v2.ResourceType
-	client               *elastic.Client
-	shouldSyncDeployment bool
+	resourceType *v2.ResourceType
+	deployments  *deploymentClients
 }
 
 func (d *deploymentUserBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
@@ -23,7 +27,7 @@ func (d *deploymentUserBuilder) ResourceType(ctx context.Context) *v2.ResourceTy
 }
 
 // Create a new connector resource for Elastic deployment user.
-func deploymentUserResource(user *elastic.DeploymentUser) (*v2.Resource, error) {
+func deploymentUserResource(deployment *deploymentClient, user *elastic.DeploymentUser) (*v2.Resource, error) {
 	firstname, lastname := helpers.SplitFullName(user.FullName)
 	profile := map[string]interface{}{
 		"first_name": firstname,
@@ -49,8 +53,9 @@ func deploymentUserResource(user *elastic.DeploymentUser) (*v2.Resource, error)
 	ret, err := rs.NewUserResource(
 		user.FullName,
 		deploymentUserResourceType,
-		user.Username,
+		deployment.resourceID(user.Username),
 		userTraitOptions,
+		rs.WithParentResourceID(deployment.parentResourceID()),
 	)
 	if err != nil {
 		return nil, err
@@ -62,11 +67,15 @@ func deploymentUserResource(user *elastic.DeploymentUser) (*v2.Resource, error)
 // List returns all the users from the database as resource objects.
 // Users include a UserTrait because they are the 'shape' of a standard user.
 func (d *deploymentUserBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId, pToken *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
-	if !d.shouldSyncDeployment {
+	deployment, err := d.deployments.forParent(ctx, parentResourceID)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	if deployment == nil {
 		return nil, "", nil, nil
 	}
 
-	users, err := d.client.ListDeploymentUsers(ctx)
+	users, err := deployment.client.ListDeploymentUsers(ctx)
 	if err != nil {
 		return nil, "", nil, fmt.Errorf("error listing deployment users: %w", err)
 	}
@@ -74,7 +83,7 @@ func (d *deploymentUserBuilder) List(ctx context.Context, parentResourceID *v2.R
 	var rv []*v2.Resource
 	for key := range users {
 		userCopy := users[key]
-		ur, err := deploymentUserResource(&userCopy)
+		ur, err := deploymentUserResource(deployment, &userCopy)
 		if err != nil {
 			return nil, "", nil, fmt.Errorf("error creating user resource for deployment user %s: %w", key, err)
 		}
@@ -84,20 +93,61 @@ func (d *deploymentUserBuilder) List(ctx context.Context, parentResourceID *v2.R
 	return rv, "", nil, nil
 }
 
-// Entitlements always returns an empty slice for users.
 func (d *deploymentUserBuilder) Entitlements(_ context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
-	return nil, "", nil, nil
+	return []*v2.Entitlement{
+		ent.NewPermissionEntitlement(
+			resource,
+			userImpersonation,
+			ent.WithGrantableTo(deploymentRoleResourceType),
+			ent.WithDisplayName(fmt.Sprintf("%s User %s", resource.DisplayName, userImpersonation)),
+			ent.WithDescription(fmt.Sprintf("Run as the %s elasticsearch user", resource.DisplayName)),
+		),
+	}, "", nil, nil
 }
 
-// Grants always returns an empty slice for users since they don't have any entitlements.
+// Grants returns the impersonate grants of the roles whose run_as patterns match the username. The grants are
+// expanded to the members of the roles.
 func (d *deploymentUserBuilder) Grants(ctx context.Context, resource *v2.Resource, pToken *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
-	return nil, "", nil, nil
+	deployment, username, err := d.deployments.forResource(ctx, resource.Id.Resource)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	roles, err := deployment.listRoles(ctx)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	var rv []*v2.Grant
+	for roleName, role := range roles {
+		if !canRunAs(deployment, role, username) {
+			continue
+		}
+
+		rr, err := deploymentRoleResource(deployment, roleName)
+		if err != nil {
+			return nil, "", nil, fmt.Errorf("error creating role resource for deployment user %s: %w", resource.Id.Resource, err)
+		}
+		rv = append(rv, grant.NewGrant(resource, userImpersonation, rr.Id, withMembershipExpansion(rr)))
+	}
+
+	return rv, "", nil, nil
+}
+
+// canRunAs reports whether any of the run_as patterns of the role of the deployment matches the username.
+func canRunAs(deployment *deploymentClient, role elastic.DeploymentRole, username string) bool {
+	for _, pattern := range role.RunAs {
+		if deployment.matchesPattern(pattern, username) {
+			return true
+		}
+	}
+
+	return false
 }
 
-func newDeploymentUserBuilder(client *elastic.Client, shouldSyncDeployment bool) *deploymentUserBuilder {
+func newDeploymentUserBuilder(deployments *deploymentClients) *deploymentUserBuilder {
 	return &deploymentUserBuilder{
-		resourceType:         deploymentUserResourceType,
-		client:               client,
-		shouldSyncDeployment: shouldSyncDeployment,
+		resourceType: deploymentUserResourceType,
+		deployments:  deployments,
 	}
 }
@@ -0,0 +1,65 @@
+package connector
+
+import (
+	"testing"
+
+	"github.com/conductorone/baton-elastic/pkg/elastic"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchesPattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		value   string
+		matches bool
+	}{
+		{"alice", "alice", true},
+		{"alice", "alice2", false},
+		{"svc-*", "svc-ingest", true},
+		{"svc-?", "svc-1", true},
+		{"svc-?", "svc-12", false},
+		{"a.b", "axb", false},
+		{`svc\*`, "svc*", true},
+		{`svc\*`, "svc-ingest", false},
+		{"/svc-[0-9]+/", "svc-42", true},
+		{"/svc-[0-9]+/", "svc-42x", false},
+		{"/(alice|bob)/", "bob", true},
+		// Lucene extensions.
+		{"/svc-@/", "svc-anything", true},
+		{`/"a.b"@/`, "a.bc", true},
+		{`/"a.b"@/`, "axbc", false},
+		{`/\d+/`, "ddd", true},
+		{`/\d+/`, "123", false},
+		{"/^admin$/", "^admin$", true},
+		{"/^admin$/", "admin", false},
+		{"/a#|b/", "b", true},
+		{"/a#|b/", "a", false},
+		{`/[\d-]+/`, "d-d", true},
+		// Operators without an RE2 equivalent match nothing.
+		{"/~(admin)/", "bob", false},
+		{"/svc-@&~(svc-admin)/", "svc-ingest", false},
+		{"/svc-<1-10>/", "svc-5", false},
+		{"/svc-[0-9/", "svc-1", false},
+	}
+
+	deployment := &deploymentClient{id: "d1"}
+	for _, test := range tests {
+		assert.Equal(t, test.matches, deployment.matchesPattern(test.pattern, test.value), "%s %s", test.pattern, test.value)
+	}
+
+	// Compiled patterns are dropped with the other data cached for the sync.
+	assert.NotEmpty(t, deployment.patterns)
+	deployment.resetCache()
+	assert.Empty(t, deployment.patterns)
+	assert.True(t, deployment.matchesPattern("svc-*", "svc-ingest"))
+}
+
+func TestCanRunAs(t *testing.T) {
+	deployment := &deploymentClient{id: "d1"}
+	role := elastic.DeploymentRole{RunAs: []string{"svc-*", "/(alice|bob)/"}}
+
+	assert.True(t, canRunAs(deployment, role, "svc-ingest"))
+	assert.True(t, canRunAs(deployment, role, "alice"))
+	assert.False(t, canRunAs(deployment, role, "carol"))
+	assert.False(t, canRunAs(deployment, elastic.DeploymentRole{}, "alice"))
+}
@@ -0,0 +1,343 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/conductorone/baton-elastic/pkg/elastic"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	ent "github.com/conductorone/baton-sdk/pkg/types/entitlement"
+	grant "github.com/conductorone/baton-sdk/pkg/types/grant"
+	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+)
+
+type deploymentBuilder struct {
+	resourceType  *v2.ResourceType
+	client        *elastic.Client
+	deployments   *deploymentClients
+	organizations *organizationCache
+}
+
+func (d *deploymentBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
+	return d.resourceType
+}
+
+// Create a new connector resource for Elastic Cloud deployment. Users, roles and role mappings of the deployment are
+// listed under it when they are reached through the Elastic Cloud proxy.
+func deploymentResource(deployment *elastic.Deployment, parentResourceID *v2.ResourceId, useCloudProxy bool) (*v2.Resource, error) {
+	profile := map[string]interface{}{
+		"deployment_id":   deployment.ID,
+		"deployment_name": deployment.Name,
+		"alias":           deployment.Alias,
+	}
+
+	if es := deployment.Elasticsearch(); es != nil {
+		profile["region"] = es.Region
+		profile["version"] = es.Info.PlanInfo.Current.Plan.Elasticsearch.Version
+	}
+
+	appTraitOptions := []rs.AppTraitOption{
+		rs.WithAppProfile(profile),
+	}
+
+	resourceOptions := []rs.ResourceOption{
+		rs.WithParentResourceID(parentResourceID),
+	}
+	if useCloudProxy && deployment.Elasticsearch() != nil {
+		resourceOptions = append(resourceOptions, withDeploymentChildResourceTypes())
+	}
+
+	ret, err := rs.NewAppResource(
+		deployment.Name,
+		deploymentResourceType,
+		deployment.ID,
+		appTraitOptions,
+		resourceOptions...,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+// Create a new connector resource for deployment configured in the deployments file.
+func configuredDeploymentResource(deployment *deploymentClient) (*v2.Resource, error) {
+	profile := map[string]interface{}{
+		"deployment_id":          deployment.id,
+		"deployment_name":        deployment.name,
+		"elasticsearch_endpoint": deployment.endpoint,
+	}
+	if deployment.kibanaEndpoint != "" {
+		profile["kibana_endpoint"] = deployment.kibanaEndpoint
+	}
+
+	appTraitOptions := []rs.AppTraitOption{
+		rs.WithAppProfile(profile),
+	}
+
+	ret, err := rs.NewAppResource(
+		deployment.name,
+		deploymentResourceType,
+		deployment.id,
+		appTraitOptions,
+		withDeploymentChildResourceTypes(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+// withDeploymentChildResourceTypes lists the elasticsearch users, roles, role mappings, external groups, API keys,
+// service accounts, cluster, index patterns and applications under the deployment.
+func withDeploymentChildResourceTypes() rs.ResourceOption {
+	return rs.WithAnnotation(
+		&v2.ChildResourceType{ResourceTypeId: deploymentUserResourceType.Id},
+		&v2.ChildResourceType{ResourceTypeId: deploymentRoleResourceType.Id},
+		&v2.ChildResourceType{ResourceTypeId: roleMappingResourceType.Id},
+		&v2.ChildResourceType{ResourceTypeId: externalGroupResourceType.Id},
+		&v2.ChildResourceType{ResourceTypeId: deploymentAPIKeyResourceType.Id},
+		&v2.ChildResourceType{ResourceTypeId: serviceAccountResourceType.Id},
+		&v2.ChildResourceType{ResourceTypeId: clusterResourceType.Id},
+		&v2.ChildResourceType{ResourceTypeId: indexPatternResourceType.Id},
+		&v2.ChildResourceType{ResourceTypeId: applicationResourceType.Id},
+	)
+}
+
+// List returns the deployments from the deployments file at the top level and the deployments of the organization or
+// all deployments of the ECE platform under it.
+func (d *deploymentBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId, pToken *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	if parentResourceID == nil {
+		var rv []*v2.Resource
+		for _, deployment := range d.deployments.configured {
+			dr, err := configuredDeploymentResource(deployment)
+			if err != nil {
+				return nil, "", nil, fmt.Errorf("error creating deployment resource for deployment %s: %w", deployment.id, err)
+			}
+			rv = append(rv, dr)
+		}
+
+		return rv, "", nil, nil
+	}
+
+	deployments, err := d.client.ListDeployments(ctx)
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("error listing deployments: %w", err)
+	}
+
+	var rv []*v2.Resource
+	for _, deployment := range deployments {
+		if parentResourceID.ResourceType == organizationResourceType.Id && !inOrganization(deployment.Metadata.OrganizationID, parentResourceID.Resource) {
+			continue
+		}
+
+		deploymentCopy := deployment
+		dr, err := deploymentResource(&deploymentCopy, parentResourceID, d.deployments.useCloudProxy)
+		if err != nil {
+			return nil, "", nil, fmt.Errorf("error creating deployment resource for deployment %s: %w", deployment.ID, err)
+		}
+		rv = append(rv, dr)
+	}
+
+	return rv, "", nil, nil
+}
+
+// Entitlements returns the Elastic Cloud deployment roles, deployments from the deployments file have none.
+func (d *deploymentBuilder) Entitlements(_ context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	if !isCloudDeployment(resource) {
+		return nil, "", nil, nil
+	}
+
+	var rv []*v2.Entitlement
+	for _, role := range deploymentRoles {
+		rv = append(rv, ent.NewAssignmentEntitlement(
+			resource,
+			role.id,
+			ent.WithGrantableTo(userResourceType, cloudAPIKeyResourceType),
+			ent.WithDisplayName(fmt.Sprintf("%s Deployment %s", resource.DisplayName, role.displayName)),
+			ent.WithDescription(fmt.Sprintf("%s of the %s Elastic Cloud deployment", role.displayName, resource.DisplayName)),
+		))
+	}
+
+	return rv, "", nil, nil
+}
+
+// Grants returns the deployment roles of organization members, including roles assigned for all deployments.
+func (d *deploymentBuilder) Grants(ctx context.Context, resource *v2.Resource, pToken *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	if !isCloudDeployment(resource) {
+		return nil, "", nil, nil
+	}
+
+	orgID, err := deploymentOrganizationID(resource)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	members, err := d.organizations.listMembers(ctx, orgID)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	var rv []*v2.Grant
+	for _, member := range members {
+		memberCopy := member
+		ur, err := userResource(&memberCopy, resource.ParentResourceId)
+		if err != nil {
+			return nil, "", nil, fmt.Errorf("error creating user resource for deployment %s: %w", resource.Id.Resource, err)
+		}
+		rv = append(rv, deploymentRoleGrants(resource, orgID, ur.Id, member.RoleAssignments)...)
+	}
+
+	keys, err := d.organizations.listCloudAPIKeys(ctx, orgID)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	for _, key := range keys {
+		keyCopy := key
+		rv = append(rv, deploymentRoleGrants(resource, orgID, cloudAPIKeyID(&keyCopy), key.RoleAssignments)...)
+	}
+
+	return rv, "", nil, nil
+}
+
+// deploymentRoleGrants returns a grant for every deployment role the principal holds for the deployment.
+func deploymentRoleGrants(resource *v2.Resource, orgID string, principal *v2.ResourceId, assignments elastic.RoleAssignments) []*v2.Grant {
+	var rv []*v2.Grant
+	granted := make(map[string]bool)
+	for _, assignment := range assignments.Deployment {
+		if granted[assignment.RoleID] || !isCloudRole(deploymentRoles, assignment.RoleID) || !inOrganization(assignment.OrganizationID, orgID) {
+			continue
+		}
+		if !assignment.All && !slices.Contains(assignment.DeploymentIDs, resource.Id.Resource) {
+			continue
+		}
+		granted[assignment.RoleID] = true
+		rv = append(rv, grant.NewGrant(resource, assignment.RoleID, principal))
+	}
+
+	return rv
+}
+
+// Grant assigns an Elastic Cloud deployment role to an organization member for a single deployment.
+func (d *deploymentBuilder) Grant(ctx context.Context, principal *v2.Resource, entitlement *v2.Entitlement) (annotations.Annotations, error) {
+	l := ctxzap.Extract(ctx)
+
+	if principal.Id.ResourceType != userResourceType.Id {
+		l.Warn(
+			"baton-elastic: only users can be granted deployment roles",
+			zap.String("principal_type", principal.Id.ResourceType),
+			zap.String("principal_id", principal.Id.Resource),
+		)
+		return nil, fmt.Errorf("baton-elastic: only users can be granted deployment roles")
+	}
+
+	orgID, err := deploymentOrganizationID(entitlement.Resource)
+	if err != nil {
+		return nil, err
+	}
+
+	roleID := entitlementSlug(entitlement)
+	if !isCloudRole(deploymentRoles, roleID) {
+		return nil, fmt.Errorf("baton-elastic: granting %s is not supported", roleID)
+	}
+
+	err = d.client.AddRoleAssignments(ctx, principal.Id.Resource, elastic.RoleAssignments{
+		Deployment: []elastic.DeploymentRoleAssignment{{
+			OrganizationID: orgID,
+			RoleID:         roleID,
+			DeploymentIDs:  []string{entitlement.Resource.Id.Resource},
+		}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("baton-elastic: failed to grant deployment role to user: %w", err)
+	}
+
+	return nil, nil
+}
+
+// Revoke removes an Elastic Cloud deployment role of an organization member for a single deployment. Roles assigned
+// for all deployments have to be revoked on the organization. Revoking a role of an API key deletes the key.
+func (d *deploymentBuilder) Revoke(ctx context.Context, grant *v2.Grant) (annotations.Annotations, error) {
+	l := ctxzap.Extract(ctx)
+	principal := grant.Principal
+	entitlement := grant.Entitlement
+
+	if principal.Id.ResourceType == cloudAPIKeyResourceType.Id {
+		return deleteCloudAPIKey(ctx, d.client, principal)
+	}
+
+	if principal.Id.ResourceType != userResourceType.Id {
+		l.Warn(
+			"baton-elastic: only users can have deployment roles revoked",
+			zap.String("principal_type", principal.Id.ResourceType),
+			zap.String("principal_id", principal.Id.Resource),
+		)
+		return nil, fmt.Errorf("baton-elastic: only users can have deployment roles revoked")
+	}
+
+	orgID, err := deploymentOrganizationID(entitlement.Resource)
+	if err != nil {
+		return nil, err
+	}
+
+	roleID := entitlementSlug(entitlement)
+	if !isCloudRole(deploymentRoles, roleID) {
+		return nil, fmt.Errorf("baton-elastic: revoking %s is not supported", roleID)
+	}
+
+	member, err := getOrgMember(ctx, d.client, orgID, principal.Id.Resource)
+	if err != nil {
+		return nil, err
+	}
+
+	deploymentID := entitlement.Resource.Id.Resource
+	for _, assignment := range member.RoleAssignments.Deployment {
+		if assignment.RoleID == roleID && assignment.All && inOrganization(assignment.OrganizationID, orgID) {
+			return nil, fmt.Errorf("baton-elastic: user %s has %s for all deployments, revoke it on the organization instead", principal.Id.Resource, roleID)
+		}
+	}
+
+	err = d.client.RemoveRoleAssignments(ctx, principal.Id.Resource, elastic.RoleAssignments{
+		Deployment: []elastic.DeploymentRoleAssignment{{
+			OrganizationID: orgID,
+			RoleID:         roleID,
+			DeploymentIDs:  []string{deploymentID},
+		}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("baton-elastic: failed to revoke deployment role from user: %w", err)
+	}
+
+	return nil, nil
+}
+
+// isCloudDeployment reports whether the deployment resource was listed from Elastic Cloud.
+func isCloudDeployment(resource *v2.Resource) bool {
+	return resource.ParentResourceId != nil && resource.ParentResourceId.ResourceType == organizationResourceType.Id
+}
+
+// deploymentOrganizationID returns the ID of the organization the deployment resource was listed under.
+func deploymentOrganizationID(resource *v2.Resource) (string, error) {
+	if resource.ParentResourceId == nil || resource.ParentResourceId.ResourceType != organizationResourceType.Id {
+		return "", fmt.Errorf("baton-elastic: deployment %s has no parent organization", resource.Id.Resource)
+	}
+
+	return resource.ParentResourceId.Resource, nil
+}
+
+func newDeploymentBuilder(client *elastic.Client, deployments *deploymentClients, organizations *organizationCache) *deploymentBuilder {
+	return &deploymentBuilder{
+		resourceType:  deploymentResourceType,
+		client:        client,
+		deployments:   deployments,
+		organizations: organizations,
+	}
+}
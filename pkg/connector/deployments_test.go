@@ -0,0 +1,58 @@
+package connector
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/conductorone/baton-elastic/pkg/elastic"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeploymentRoleGrantRevoke(t *testing.T) {
+	server := newTestServer(t, map[string]testResponse{
+		"GET /api/v1/organizations/org1/members": {http.StatusOK, `{"members":[
+			{"user_id":"u1","role_assignments":{"deployment":[{"organization_id":"org1","role_id":"deployment-admin","all":true}]}},
+			{"user_id":"u2","role_assignments":{"deployment":[{"organization_id":"org1","role_id":"deployment-editor","deployment_ids":["d1"]}]}}
+		]}`},
+		"POST /api/v1/users/u2/role_assignments":   {http.StatusOK, `{}`},
+		"DELETE /api/v1/users/u2/role_assignments": {http.StatusOK, `{}`},
+	})
+
+	ctx := context.Background()
+	client := elastic.NewClient(server.Client(), "", "", "key", "").WithBaseURL(server.URL)
+	builder := newDeploymentBuilder(client, newDeploymentClients(client, false, nil), newOrganizationCache(client))
+
+	organization, err := organizationResource(elastic.Organization{ID: "org1", Name: "Org"})
+	assert.Nil(t, err)
+	deployment, err := deploymentResource(&elastic.Deployment{ID: "d1", Name: "Prod"}, organization.Id, false)
+	assert.Nil(t, err)
+	admin, err := userResource(&elastic.User{UserID: "u1"}, organization.Id)
+	assert.Nil(t, err)
+	editor, err := userResource(&elastic.User{UserID: "u2"}, organization.Id)
+	assert.Nil(t, err)
+
+	// Roles granted and revoked on a deployment are assigned for that deployment only.
+	_, err = builder.Grant(ctx, editor, resourceEntitlement(t, builder, deployment, "deployment-viewer"))
+	assert.Nil(t, err)
+	_, err = builder.Revoke(ctx, &v2.Grant{Entitlement: resourceEntitlement(t, builder, deployment, "deployment-editor"), Principal: editor})
+	assert.Nil(t, err)
+
+	// Roles assigned for all deployments can't be revoked for a single deployment.
+	_, err = builder.Revoke(ctx, &v2.Grant{Entitlement: resourceEntitlement(t, builder, deployment, "deployment-admin"), Principal: admin})
+	assert.NotNil(t, err)
+
+	assert.Equal(t, []testRequest{
+		{
+			method: http.MethodPost,
+			path:   "/api/v1/users/u2/role_assignments",
+			body:   `{"deployment":[{"organization_id":"org1","role_id":"deployment-viewer","deployment_ids":["d1"]}]}`,
+		},
+		{
+			method: http.MethodDelete,
+			path:   "/api/v1/users/u2/role_assignments",
+			body:   `{"deployment":[{"organization_id":"org1","role_id":"deployment-editor","deployment_ids":["d1"]}]}`,
+		},
+	}, server.changes())
+}
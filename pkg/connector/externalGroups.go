@@ -0,0 +1,116 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
+)
+
+// externalGroupFields are the role mapping rule fields whose values identify principals of an external identity
+// provider, i.e. the groups of SAML, OIDC and LDAP users and the distinguished names of LDAP users.
+var externalGroupFields = []string{"groups", "dn"}
+
+type externalGroupBuilder struct {
+	resourceType *v2.ResourceType
+	deployments  *deploymentClients
+}
+
+func (e *externalGroupBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
+	return e.resourceType
+}
+
+// Create a new connector resource for a group or distinguished name of an external identity provider that role
+// mappings of Elastic deployment match. The field is part of the ID, as the same value may appear in both fields.
+func externalGroupResource(deployment *deploymentClient, field, value string) (*v2.Resource, error) {
+	profile := map[string]interface{}{
+		"field": field,
+		"value": value,
+	}
+
+	ret, err := rs.NewGroupResource(
+		value,
+		externalGroupResourceType,
+		deployment.resourceID(field+"/"+value),
+		[]rs.GroupTraitOption{rs.WithGroupProfile(profile)},
+		rs.WithParentResourceID(deployment.parentResourceID()),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+// List returns the groups and distinguished names the role mappings of the deployment match.
+func (e *externalGroupBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId, pToken *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	deployment, err := e.deployments.forParent(ctx, parentResourceID)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	if deployment == nil {
+		return nil, "", nil, nil
+	}
+
+	mappings, err := deployment.client.ListDeploymentRoleMapping(ctx)
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("error listing role mappings: %w", err)
+	}
+
+	var rv []*v2.Resource
+	for _, field := range externalGroupFields {
+		var values []string
+		for _, mapping := range mappings {
+			for _, value := range roleMappingFieldValues(mapping, field) {
+				if !slices.Contains(values, value) {
+					values = append(values, value)
+				}
+			}
+		}
+		slices.Sort(values)
+
+		for _, value := range values {
+			gr, err := externalGroupResource(deployment, field, value)
+			if err != nil {
+				return nil, "", nil, fmt.Errorf("error creating external group resource for %s %s: %w", field, value, err)
+			}
+			rv = append(rv, gr)
+		}
+	}
+
+	return rv, "", nil, nil
+}
+
+// Entitlements always returns an empty slice for external groups, their members are managed by the identity provider.
+func (e *externalGroupBuilder) Entitlements(_ context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	return nil, "", nil, nil
+}
+
+// Grants always returns an empty slice for external groups since they don't have any entitlements.
+func (e *externalGroupBuilder) Grants(ctx context.Context, resource *v2.Resource, pToken *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	return nil, "", nil, nil
+}
+
+// splitExternalGroup splits the name of an external group resource into the role mapping field and its value.
+// Values are kept as they are and may contain "/", e.g. in distinguished names. Neither this nor forResource
+// escapes them, as both cut at the first "/" and neither deployment IDs nor the field names contain one.
+func splitExternalGroup(name string) (string, string, error) {
+	field, value, ok := strings.Cut(name, "/")
+	if !ok || !slices.Contains(externalGroupFields, field) {
+		return "", "", fmt.Errorf("baton-elastic: invalid external group %s", name)
+	}
+
+	return field, value, nil
+}
+
+func newExternalGroupBuilder(deployments *deploymentClients) *externalGroupBuilder {
+	return &externalGroupBuilder{
+		resourceType: externalGroupResourceType,
+		deployments:  deployments,
+	}
+}
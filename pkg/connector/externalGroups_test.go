@@ -0,0 +1,30 @@
+package connector
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExternalGroupResourceID(t *testing.T) {
+	ctx := context.Background()
+	deployment := &deploymentClient{id: "d1"}
+	deployments := newDeploymentClients(nil, false, []*deploymentClient{deployment})
+
+	resource, err := externalGroupResource(deployment, "dn", "cn=admins/ops,dc=example,dc=com")
+	assert.Nil(t, err)
+	assert.Equal(t, "d1/dn/cn=admins/ops,dc=example,dc=com", resource.Id.Resource)
+
+	dc, name, err := deployments.forResource(ctx, resource.Id.Resource)
+	assert.Nil(t, err)
+	assert.Equal(t, deployment, dc)
+
+	field, value, err := splitExternalGroup(name)
+	assert.Nil(t, err)
+	assert.Equal(t, "dn", field)
+	assert.Equal(t, "cn=admins/ops,dc=example,dc=com", value)
+
+	_, _, err = splitExternalGroup("username/alice")
+	assert.NotNil(t, err)
+}
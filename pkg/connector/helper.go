@@ -1,27 +1,153 @@
 package connector
 
-import "strings"
+import (
+	"regexp"
+	"strings"
+	"unicode"
 
-type Utility struct {
-	Data string
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	ent "github.com/conductorone/baton-sdk/pkg/types/entitlement"
+	grant "github.com/conductorone/baton-sdk/pkg/types/grant"
+)
+
+// entitlementSlug returns the name the entitlement was created with, e.g. "member" for "organization:123:member".
+// Resource IDs and names may contain colons, e.g. the index pattern "remote:logs-*", so the name is what follows the
+// type and ID of the entitlement resource.
+func entitlementSlug(entitlement *v2.Entitlement) string {
+	if resource := entitlement.GetResource(); resource.GetId() != nil {
+		prefix := resource.Id.ResourceType + ":" + resource.Id.Resource + ":"
+		if slug, ok := strings.CutPrefix(entitlement.Id, prefix); ok {
+			return slug
+		}
+	}
+	if entitlement.Slug != "" {
+		return entitlement.Slug
+	}
+
+	parts := strings.Split(entitlement.Id, ":")
+	return parts[len(parts)-1]
 }
 
-func (u *Utility) TrimPrefix(prefix string) *Utility {
-	return &Utility{
-		Data: strings.TrimPrefix(u.Data, prefix),
+// compilePattern compiles an elasticsearch name pattern, which is either a wildcard pattern with * and ? or a Lucene
+// regular expression enclosed in slashes, into an expression matching whole values. It returns nil if the pattern is
+// not supported: regular expressions using the complement (~), intersection (&) or numeric interval (<n-m>) operators
+// have no RE2 equivalent.
+func compilePattern(pattern string) *regexp.Regexp {
+	var expr string
+	if len(pattern) >= 2 && strings.HasPrefix(pattern, "/") && strings.HasSuffix(pattern, "/") {
+		var ok bool
+		expr, ok = luceneRegexp(pattern[1 : len(pattern)-1])
+		if !ok {
+			return nil
+		}
+	} else {
+		expr = wildcardRegexp(pattern)
+	}
+
+	re, err := regexp.Compile("^(?s:" + expr + ")$")
+	if err != nil {
+		return nil
 	}
+
+	return re
 }
 
-func (u *Utility) TrimSuffix(suffix string) *Utility {
-	return &Utility{
-		Data: strings.TrimSuffix(u.Data, suffix),
+// wildcardRegexp translates a wildcard pattern, where * matches any string, ? any character and \ escapes the next
+// character, into RE2 syntax.
+func wildcardRegexp(pattern string) string {
+	var sb strings.Builder
+	runes := []rune(pattern)
+	for i := 0; i < len(runes); i++ {
+		switch c := runes[i]; {
+		case c == '*':
+			sb.WriteString(".*")
+		case c == '?':
+			sb.WriteString(".")
+		case c == '\\' && i+1 < len(runes):
+			i++
+			sb.WriteString(regexp.QuoteMeta(string(runes[i])))
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
 	}
+
+	return sb.String()
 }
 
-func (u *Utility) Split(sep string) []string {
-	return strings.Split(u.Data, sep)
+// luceneRegexp translates a Lucene regular expression into RE2 syntax. Lucene expressions always match whole values,
+// \ escapes any character rather than starting a class like \d, quotes enclose literal strings, @ matches any string
+// and # matches nothing. ^ and $ are plain characters. It reports false for the operators RE2 has no equivalent of.
+// https://www.elastic.co/guide/en/elasticsearch/reference/current/regexp-syntax.html
+func luceneRegexp(expr string) (string, bool) {
+	var sb strings.Builder
+	runes := []rune(expr)
+	inClass := false
+	for i := 0; i < len(runes); i++ {
+		c := runes[i]
+		if inClass {
+			switch {
+			case c == '\\':
+				if i+1 == len(runes) {
+					return "", false
+				}
+				i++
+				if unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) {
+					sb.WriteRune(runes[i])
+				} else {
+					sb.WriteString(`\` + string(runes[i]))
+				}
+			case c == ']':
+				sb.WriteRune(c)
+				inClass = false
+			case c == '[':
+				sb.WriteString(`\[`)
+			default:
+				sb.WriteRune(c)
+			}
+			continue
+		}
+
+		switch c {
+		case '\\':
+			if i+1 == len(runes) {
+				return "", false
+			}
+			i++
+			sb.WriteString(regexp.QuoteMeta(string(runes[i])))
+		case '"':
+			end := strings.IndexRune(string(runes[i+1:]), '"')
+			if end < 0 {
+				return "", false
+			}
+			literal := string(runes[i+1:])[:end]
+			sb.WriteString(regexp.QuoteMeta(literal))
+			i += len([]rune(literal)) + 1
+		case '@':
+			sb.WriteString(".*")
+		case '#':
+			sb.WriteString(`[^\x00-\x{10FFFF}]`)
+		case '~', '&', '<':
+			return "", false
+		case '[':
+			sb.WriteRune(c)
+			inClass = true
+			if i+1 < len(runes) && runes[i+1] == '^' {
+				i++
+				sb.WriteRune('^')
+			}
+		case '.', '?', '+', '*', '|', '{', '}', '(', ')', ']':
+			sb.WriteRune(c)
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+
+	return sb.String(), !inClass
 }
 
-func (u *Utility) ToString() string {
-	return u.Data
+// withMembershipExpansion expands a grant to a role or role mapping to the members of the role or role mapping.
+func withMembershipExpansion(principal *v2.Resource) grant.GrantOption {
+	return grant.WithAnnotation(&v2.GrantExpandable{
+		EntitlementIds: []string{ent.NewEntitlementID(principal, roleMembership)},
+	})
 }
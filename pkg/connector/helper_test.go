@@ -0,0 +1,105 @@
+package connector
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/connectorbuilder"
+	ent "github.com/conductorone/baton-sdk/pkg/types/entitlement"
+	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEntitlementSlug(t *testing.T) {
+	resource, err := rs.NewResource("remote:logs-*", indexPatternResourceType, "d1/remote:logs-*")
+	assert.Nil(t, err)
+
+	entitlement := ent.NewPermissionEntitlement(resource, "read")
+	assert.Equal(t, "read", entitlementSlug(entitlement))
+
+	entitlement = ent.NewPermissionEntitlement(resource, "space:read")
+	assert.Equal(t, "space:read", entitlementSlug(entitlement))
+
+	// Entitlements passed without their resource fall back to the slug.
+	assert.Equal(t, "space:read", entitlementSlug(&v2.Entitlement{Id: entitlement.Id, Slug: "space:read"}))
+	assert.Equal(t, "member", entitlementSlug(&v2.Entitlement{Id: "organization:123:member"}))
+}
+
+// testResponse is the response of a test server to a request.
+type testResponse struct {
+	status int
+	body   string
+}
+
+// testRequest is a request received by a test server.
+type testRequest struct {
+	method string
+	path   string
+	body   string
+}
+
+// testServer responds to requests with the response of their method and path, or with 404 Not Found, and records
+// them.
+type testServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	requests []testRequest
+}
+
+func newTestServer(t *testing.T, responses map[string]testResponse) *testServer {
+	server := &testServer{}
+	server.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		assert.Nil(t, err)
+
+		server.mu.Lock()
+		server.requests = append(server.requests, testRequest{method: r.Method, path: r.URL.Path, body: string(body)})
+		server.mu.Unlock()
+
+		response, ok := responses[r.Method+" "+r.URL.Path]
+		if !ok {
+			response = testResponse{status: http.StatusNotFound, body: `{}`}
+		}
+		w.WriteHeader(response.status)
+		_, _ = w.Write([]byte(response.body))
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+// changes returns the requests the server received other than GET requests.
+func (s *testServer) changes() []testRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var rv []testRequest
+	for _, request := range s.requests {
+		if request.method != http.MethodGet {
+			rv = append(rv, request)
+		}
+	}
+
+	return rv
+}
+
+// resourceEntitlement returns the entitlement of the resource with the slug.
+func resourceEntitlement(t *testing.T, builder connectorbuilder.ResourceSyncer, resource *v2.Resource, slug string) *v2.Entitlement {
+	entitlements, _, _, err := builder.Entitlements(context.Background(), resource, nil)
+	assert.Nil(t, err)
+
+	for _, entitlement := range entitlements {
+		if entitlementSlug(entitlement) == slug {
+			return entitlement
+		}
+	}
+
+	t.Fatalf("%s has no %s entitlement", resource.Id.Resource, slug)
+	return nil
+}
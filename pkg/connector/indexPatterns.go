@@ -0,0 +1,167 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/conductorone/baton-elastic/pkg/elastic"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	ent "github.com/conductorone/baton-sdk/pkg/types/entitlement"
+	grant "github.com/conductorone/baton-sdk/pkg/types/grant"
+	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
+)
+
+type indexPatternBuilder struct {
+	resourceType *v2.ResourceType
+	deployments  *deploymentClients
+}
+
+func (i *indexPatternBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
+	return i.resourceType
+}
+
+// Create a new connector resource for an index name or pattern that appears in the roles of Elastic deployment.
+func indexPatternResource(deployment *deploymentClient, pattern string) (*v2.Resource, error) {
+	ret, err := rs.NewResource(
+		pattern,
+		indexPatternResourceType,
+		deployment.resourceID(pattern),
+		rs.WithParentResourceID(deployment.parentResourceID()),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+// List returns every index name or pattern the roles of the deployment hold privileges on.
+func (i *indexPatternBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId, pToken *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	deployment, err := i.deployments.forParent(ctx, parentResourceID)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	if deployment == nil {
+		return nil, "", nil, nil
+	}
+
+	roles, err := deployment.listRoles(ctx)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	var patterns []string
+	for _, role := range roles {
+		for _, indices := range role.Indices {
+			for _, name := range indices.Names {
+				if !slices.Contains(patterns, name) {
+					patterns = append(patterns, name)
+				}
+			}
+		}
+	}
+	slices.Sort(patterns)
+
+	var rv []*v2.Resource
+	for _, pattern := range patterns {
+		ir, err := indexPatternResource(deployment, pattern)
+		if err != nil {
+			return nil, "", nil, fmt.Errorf("error creating index pattern resource for index pattern %s: %w", pattern, err)
+		}
+		rv = append(rv, ir)
+	}
+
+	return rv, "", nil, nil
+}
+
+// Entitlements returns an entitlement for every privilege the roles of the deployment hold on the index pattern.
+func (i *indexPatternBuilder) Entitlements(ctx context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	deployment, pattern, err := i.deployments.forResource(ctx, resource.Id.Resource)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	roles, err := deployment.listRoles(ctx)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	var privileges []string
+	for _, role := range roles {
+		for _, privilege := range indexPrivileges(role, pattern) {
+			if !slices.Contains(privileges, privilege) {
+				privileges = append(privileges, privilege)
+			}
+		}
+	}
+	slices.Sort(privileges)
+
+	var rv []*v2.Entitlement
+	for _, privilege := range privileges {
+		rv = append(rv, ent.NewPermissionEntitlement(
+			resource,
+			privilege,
+			ent.WithGrantableTo(deploymentRoleResourceType),
+			ent.WithDisplayName(fmt.Sprintf("%s Index %s", resource.DisplayName, privilege)),
+			ent.WithDescription(fmt.Sprintf("%s privilege on the %s indices", privilege, resource.DisplayName)),
+		))
+	}
+
+	return rv, "", nil, nil
+}
+
+// Grants returns the privileges the roles of the deployment hold on the index pattern. The grants are expanded to
+// the members of the roles.
+func (i *indexPatternBuilder) Grants(ctx context.Context, resource *v2.Resource, pToken *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	deployment, pattern, err := i.deployments.forResource(ctx, resource.Id.Resource)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	roles, err := deployment.listRoles(ctx)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	var rv []*v2.Grant
+	for roleName, role := range roles {
+		rr, err := deploymentRoleResource(deployment, roleName)
+		if err != nil {
+			return nil, "", nil, fmt.Errorf("error creating role resource for index pattern %s: %w", resource.Id.Resource, err)
+		}
+
+		for _, privilege := range indexPrivileges(role, pattern) {
+			rv = append(rv, grant.NewGrant(resource, privilege, rr.Id, withMembershipExpansion(rr)))
+		}
+	}
+
+	return rv, "", nil, nil
+}
+
+// indexPrivileges returns the privileges the role holds on the index name or pattern.
+func indexPrivileges(role elastic.DeploymentRole, pattern string) []string {
+	var rv []string
+	for _, indices := range role.Indices {
+		if !slices.Contains(indices.Names, pattern) {
+			continue
+		}
+
+		for _, privilege := range indices.Privileges {
+			if !slices.Contains(rv, privilege) {
+				rv = append(rv, privilege)
+			}
+		}
+	}
+
+	return rv
+}
+
+func newIndexPatternBuilder(deployments *deploymentClients) *indexPatternBuilder {
+	return &indexPatternBuilder{
+		resourceType: indexPatternResourceType,
+		deployments:  deployments,
+	}
+}
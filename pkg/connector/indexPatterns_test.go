@@ -0,0 +1,24 @@
+package connector
+
+import (
+	"testing"
+
+	"github.com/conductorone/baton-elastic/pkg/elastic"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIndexPrivileges(t *testing.T) {
+	role := elastic.DeploymentRole{
+		Indices: []elastic.RoleIndexPrivileges{
+			{Names: []string{"logs-*", "metrics-*"}, Privileges: []string{"read", "view_index_metadata"}},
+			{Names: []string{"logs-*"}, Privileges: []string{"read", "write"}},
+			{Names: []string{"logs-app"}, Privileges: []string{"all"}},
+		},
+	}
+
+	assert.Equal(t, []string{"read", "view_index_metadata", "write"}, indexPrivileges(role, "logs-*"))
+	assert.Equal(t, []string{"read", "view_index_metadata"}, indexPrivileges(role, "metrics-*"))
+	// Patterns are not matched against each other, every name and pattern of the roles is its own resource.
+	assert.Equal(t, []string{"all"}, indexPrivileges(role, "logs-app"))
+	assert.Empty(t, indexPrivileges(role, "traces-*"))
+}
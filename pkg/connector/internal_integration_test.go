@@ -16,14 +16,14 @@ func TestRoleMappingBuilderGrants(t *testing.T) {
 	cli := getClientForTesting(ctx)
 	assert.Nil(t, cli)
 
+	deployment := &deploymentClient{id: "test", client: cli}
 	p := &roleMappingBuilder{
-		resourceType:         roleMappingResourceType,
-		client:               cli,
-		shouldSyncDeployment: true,
+		resourceType: roleMappingResourceType,
+		deployments:  newDeploymentClients(cli, false, []*deploymentClient{deployment}),
 	}
 
 	roleMapping := "mapping7"
-	resource, err := roleMappingResource(roleMapping)
+	resource, err := roleMappingResource(deployment, roleMapping)
 	assert.Nil(t, err)
 
 	_, _, _, err1 := p.Grants(ctx, resource, pToken)
@@ -38,13 +38,13 @@ func TestGetUsers(t *testing.T) {
 	cli := getClientForTesting(ctx)
 	assert.Nil(t, cli)
 
+	deployment := &deploymentClient{id: "test", client: cli}
 	p := &roleMappingBuilder{
-		resourceType:         roleMappingResourceType,
-		client:               cli,
-		shouldSyncDeployment: true,
+		resourceType: roleMappingResourceType,
+		deployments:  newDeploymentClients(cli, false, []*deploymentClient{deployment}),
 	}
 
-	roleMapping := "mapping7"
+	roleMapping := deployment.resourceID("mapping7")
 	_, err := p.GetRoleMappingUsers(ctx, roleMapping)
 	assert.Nil(t, err)
 }
@@ -0,0 +1,165 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/conductorone/baton-elastic/pkg/elastic"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+)
+
+type invitationBuilder struct {
+	resourceType *v2.ResourceType
+	client       *elastic.Client
+}
+
+func (i *invitationBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
+	return i.resourceType
+}
+
+// Create a new connector resource for a pending invitation to an Elastic organization. The invitee has no Elastic
+// user ID until the invitation is accepted, so the invitation is identified by the organization and the invited email.
+func invitationResource(invitation *elastic.Invitation, parentResourceID *v2.ResourceId) (*v2.Resource, error) {
+	profile := map[string]interface{}{
+		"email":      invitation.Email,
+		"org_id":     invitation.OrganizationID,
+		"roles":      strings.Join(invitedRoles(invitation.RoleAssignments), ","),
+		"created_at": invitation.CreatedAt,
+		"expires_at": invitation.ExpiresAt,
+	}
+
+	userTraitOptions := []rs.UserTraitOption{
+		rs.WithUserProfile(profile),
+		rs.WithEmail(invitation.Email, true),
+	}
+
+	ret, err := rs.NewUserResource(
+		invitation.Email,
+		invitationResourceType,
+		invitationResourceID(parentResourceID.Resource, invitation.Email),
+		userTraitOptions,
+		rs.WithParentResourceID(parentResourceID),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+// List returns the pending invitations of the organization. Accepted and expired invitations grant no access and
+// are skipped. Invitations are not synced when the API key may not list them.
+func (i *invitationBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId, pToken *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	l := ctxzap.Extract(ctx)
+
+	if parentResourceID == nil || parentResourceID.ResourceType != organizationResourceType.Id {
+		return nil, "", nil, nil
+	}
+
+	invitations, err := listPendingInvitations(ctx, i.client, parentResourceID.Resource)
+	if err != nil {
+		if !elastic.IsForbidden(err) {
+			return nil, "", nil, err
+		}
+		l.Warn(
+			"baton-elastic: skipping organization invitations",
+			zap.String("organization_id", parentResourceID.Resource),
+			zap.Error(err),
+		)
+		return nil, "", nil, nil
+	}
+
+	var rv []*v2.Resource
+	for _, invitation := range invitations {
+		invitationCopy := invitation
+		ir, err := invitationResource(&invitationCopy, parentResourceID)
+		if err != nil {
+			return nil, "", nil, fmt.Errorf("error creating invitation resource: %w", err)
+		}
+		rv = append(rv, ir)
+	}
+
+	return rv, "", nil, nil
+}
+
+// Entitlements always returns an empty slice for invitations.
+func (i *invitationBuilder) Entitlements(_ context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	return nil, "", nil, nil
+}
+
+// Grants always returns an empty slice for invitations since they don't have any entitlements.
+func (i *invitationBuilder) Grants(ctx context.Context, resource *v2.Resource, pToken *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	return nil, "", nil, nil
+}
+
+// invitationResourceID returns the ID of the invitation resource of the email to the organization. The same email may
+// be invited to several organizations.
+func invitationResourceID(orgID, email string) string {
+	return orgID + "/" + email
+}
+
+// invitationEmail returns the invited email of the invitation resource.
+func invitationEmail(resource *v2.Resource) (string, error) {
+	_, email, ok := strings.Cut(resource.Id.Resource, "/")
+	if !ok || email == "" {
+		return "", fmt.Errorf("baton-elastic: invalid invitation %s", resource.Id.Resource)
+	}
+
+	return email, nil
+}
+
+// listPendingInvitations returns the invitations to the organization that are neither accepted nor expired.
+func listPendingInvitations(ctx context.Context, client *elastic.Client, orgID string) ([]elastic.Invitation, error) {
+	invitations, err := client.ListOrgInvitations(ctx, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("error listing organization invitations: %w", err)
+	}
+
+	var rv []elastic.Invitation
+	for _, invitation := range invitations {
+		if invitation.AcceptedAt != "" || invitation.Expired {
+			continue
+		}
+		rv = append(rv, invitation)
+	}
+
+	return rv, nil
+}
+
+// invitedRoles returns the IDs of the roles the invitee will be assigned.
+func invitedRoles(assignments elastic.RoleAssignments) []string {
+	var rv []string
+	add := func(roleID string) {
+		if !slices.Contains(rv, roleID) {
+			rv = append(rv, roleID)
+		}
+	}
+
+	for _, assignment := range assignments.Organization {
+		add(assignment.RoleID)
+	}
+	for _, assignment := range assignments.Deployment {
+		add(assignment.RoleID)
+	}
+	for _, projectType := range elastic.ProjectTypes {
+		for _, assignment := range assignments.Project.ForType(projectType) {
+			add(assignment.RoleID)
+		}
+	}
+
+	return rv
+}
+
+func newInvitationBuilder(client *elastic.Client) *invitationBuilder {
+	return &invitationBuilder{
+		resourceType: invitationResourceType,
+		client:       client,
+	}
+}
@@ -0,0 +1,70 @@
+package connector
+
+import (
+	"context"
+	"sync"
+
+	"github.com/conductorone/baton-elastic/pkg/elastic"
+)
+
+// organizationCache caches the members and Elastic Cloud API keys of organizations for the current sync, as the
+// grants of every deployment and project of an organization are derived from them.
+type organizationCache struct {
+	client *elastic.Client
+
+	mu      sync.Mutex
+	members map[string][]elastic.User
+	keys    map[string][]elastic.CloudAPIKey
+}
+
+// listMembers returns the members of the organization, fetched once per sync.
+func (o *organizationCache) listMembers(ctx context.Context, orgID string) ([]elastic.User, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if members, ok := o.members[orgID]; ok {
+		return members, nil
+	}
+
+	members, err := o.client.ListOrgMembers(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	o.members[orgID] = members
+
+	return members, nil
+}
+
+// listCloudAPIKeys returns the Elastic Cloud API keys of the organization, fetched once per sync.
+func (o *organizationCache) listCloudAPIKeys(ctx context.Context, orgID string) ([]elastic.CloudAPIKey, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if keys, ok := o.keys[orgID]; ok {
+		return keys, nil
+	}
+
+	keys, err := listOrgCloudAPIKeys(ctx, o.client, orgID)
+	if err != nil {
+		return nil, err
+	}
+	o.keys[orgID] = keys
+
+	return keys, nil
+}
+
+// reset drops the members and keys cached during the previous sync.
+func (o *organizationCache) reset() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.members = make(map[string][]elastic.User)
+	o.keys = make(map[string][]elastic.CloudAPIKey)
+}
+
+func newOrganizationCache(client *elastic.Client) *organizationCache {
+	o := &organizationCache{client: client}
+	o.reset()
+
+	return o
+}
@@ -3,6 +3,7 @@ package connector
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"github.com/conductorone/baton-elastic/pkg/elastic"
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
@@ -11,13 +12,38 @@ import (
 	ent "github.com/conductorone/baton-sdk/pkg/types/entitlement"
 	grant "github.com/conductorone/baton-sdk/pkg/types/grant"
 	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
 )
 
-const orgMembership = "member"
+const (
+	orgMembership         = "member"
+	organizationAdminRole = "organization-admin"
+)
+
+// cloudRole is an Elastic Cloud role that can be assigned to organization members.
+type cloudRole struct {
+	id          string
+	displayName string
+	description string
+}
+
+var (
+	organizationRoles = []cloudRole{
+		{id: organizationAdminRole, displayName: "Admin", description: "Manage the %s Elastic organization, its members and all of its deployments"},
+		{id: "billing-admin", displayName: "Billing admin", description: "Manage billing of the %s Elastic organization"},
+	}
+	deploymentRoles = []cloudRole{
+		{id: "deployment-admin", displayName: "Deployment admin", description: "Manage all deployments in the %s Elastic organization"},
+		{id: "deployment-editor", displayName: "Deployment editor", description: "Edit all deployments in the %s Elastic organization"},
+		{id: "deployment-viewer", displayName: "Deployment viewer", description: "View all deployments in the %s Elastic organization"},
+	}
+)
 
 type organizationBuilder struct {
 	resourceType *v2.ResourceType
 	client       *elastic.Client
+	shouldSync   bool
 }
 
 func (r *organizationBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
@@ -32,6 +58,10 @@ func organizationResource(organization elastic.Organization) (*v2.Resource, erro
 		organization.ID,
 		rs.WithAnnotation(
 			&v2.ChildResourceType{ResourceTypeId: userResourceType.Id},
+			&v2.ChildResourceType{ResourceTypeId: deploymentResourceType.Id},
+			&v2.ChildResourceType{ResourceTypeId: projectResourceType.Id},
+			&v2.ChildResourceType{ResourceTypeId: invitationResourceType.Id},
+			&v2.ChildResourceType{ResourceTypeId: cloudAPIKeyResourceType.Id},
 		))
 
 	if err != nil {
@@ -42,6 +72,10 @@ func organizationResource(organization elastic.Organization) (*v2.Resource, erro
 }
 
 func (r *organizationBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId, pToken *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	if !r.shouldSync {
+		return nil, "", nil, nil
+	}
+
 	orgs, err := r.client.ListOrganizations(ctx)
 	if err != nil {
 		return nil, "", nil, fmt.Errorf("error listing organizations: %w", err)
@@ -62,7 +96,7 @@ func (r *organizationBuilder) List(ctx context.Context, parentResourceID *v2.Res
 func (r *organizationBuilder) Entitlements(_ context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
 	var rv []*v2.Entitlement
 	assignmentOptions := []ent.EntitlementOption{
-		ent.WithGrantableTo(userResourceType),
+		ent.WithGrantableTo(userResourceType, invitationResourceType),
 		ent.WithDisplayName(fmt.Sprintf("%s Organization %s", resource.DisplayName, orgMembership)),
 		ent.WithDescription(fmt.Sprintf("Member of %s Elastic organization", resource.DisplayName)),
 	}
@@ -73,10 +107,34 @@ func (r *organizationBuilder) Entitlements(_ context.Context, resource *v2.Resou
 		assignmentOptions...,
 	))
 
+	for _, role := range append(organizationRoles, deploymentRoles...) {
+		rv = append(rv, ent.NewAssignmentEntitlement(
+			resource,
+			role.id,
+			ent.WithGrantableTo(userResourceType, cloudAPIKeyResourceType),
+			ent.WithDisplayName(fmt.Sprintf("%s Organization %s", resource.DisplayName, role.displayName)),
+			ent.WithDescription(fmt.Sprintf(role.description, resource.DisplayName)),
+		))
+	}
+
+	for _, projectType := range elastic.ProjectTypes {
+		for _, role := range projectRoles[projectType] {
+			rv = append(rv, ent.NewAssignmentEntitlement(
+				resource,
+				role.id,
+				ent.WithGrantableTo(userResourceType, cloudAPIKeyResourceType),
+				ent.WithDisplayName(fmt.Sprintf("%s Organization %s %s", resource.DisplayName, projectTypeNames[projectType], role.displayName)),
+				ent.WithDescription(fmt.Sprintf("%s of all %s projects in the %s Elastic organization", role.displayName, projectTypeNames[projectType], resource.DisplayName)),
+			))
+		}
+	}
+
 	return rv, "", nil, nil
 }
 
 func (r *organizationBuilder) Grants(ctx context.Context, resource *v2.Resource, pToken *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	l := ctxzap.Extract(ctx)
+
 	members, err := r.client.ListOrgMembers(ctx, resource.Id.Resource)
 	if err != nil {
 		return nil, "", nil, err
@@ -91,14 +149,427 @@ func (r *organizationBuilder) Grants(ctx context.Context, resource *v2.Resource,
 		}
 		gr := grant.NewGrant(resource, orgMembership, ur.Id)
 		rv = append(rv, gr)
+		rv = append(rv, organizationRoleGrants(resource, ur.Id, member.RoleAssignments)...)
+	}
+
+	keys, err := listOrgCloudAPIKeys(ctx, r.client, resource.Id.Resource)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	for _, key := range keys {
+		keyCopy := key
+		rv = append(rv, organizationRoleGrants(resource, cloudAPIKeyID(&keyCopy), key.RoleAssignments)...)
+	}
+
+	// Invitations may not be visible to the API key, which must not fail the grants of the members.
+	invitations, err := listPendingInvitations(ctx, r.client, resource.Id.Resource)
+	if err != nil {
+		if !elastic.IsForbidden(err) {
+			return nil, "", nil, err
+		}
+		l.Warn(
+			"baton-elastic: skipping membership grants of organization invitations",
+			zap.String("organization_id", resource.Id.Resource),
+			zap.Error(err),
+		)
+	}
+
+	for _, invitation := range invitations {
+		invitationCopy := invitation
+		ir, err := invitationResource(&invitationCopy, resource.Id)
+		if err != nil {
+			return nil, "", nil, fmt.Errorf("error creating invitation resource for organization %s: %w", resource.Id.Resource, err)
+		}
+		rv = append(rv, grant.NewGrant(resource, orgMembership, ir.Id))
 	}
 
 	return rv, "", nil, nil
 }
 
-func newOrganizationBuilder(client *elastic.Client) *organizationBuilder {
+// organizationRoleGrants returns a grant for every organization role the principal holds in the organization.
+// Deployment and project roles are only granted on the organization when they are assigned for all of its deployments
+// or all of its projects of the type, assignments for individual deployments and projects are granted on their
+// resources.
+func organizationRoleGrants(resource *v2.Resource, principal *v2.ResourceId, assignments elastic.RoleAssignments) []*v2.Grant {
+	var rv []*v2.Grant
+	granted := make(map[string]bool)
+	for _, assignment := range assignments.Organization {
+		if !inOrganization(assignment.OrganizationID, resource.Id.Resource) || granted[assignment.RoleID] || !isCloudRole(organizationRoles, assignment.RoleID) {
+			continue
+		}
+		granted[assignment.RoleID] = true
+		rv = append(rv, grant.NewGrant(resource, assignment.RoleID, principal))
+	}
+
+	for _, assignment := range assignments.Deployment {
+		if !assignment.All || !inOrganization(assignment.OrganizationID, resource.Id.Resource) || granted[assignment.RoleID] || !isCloudRole(deploymentRoles, assignment.RoleID) {
+			continue
+		}
+		granted[assignment.RoleID] = true
+		rv = append(rv, grant.NewGrant(resource, assignment.RoleID, principal))
+	}
+
+	for _, projectType := range elastic.ProjectTypes {
+		for _, assignment := range assignments.Project.ForType(projectType) {
+			if !assignment.All || !inOrganization(assignment.OrganizationID, resource.Id.Resource) || granted[assignment.RoleID] || !isCloudRole(projectRoles[projectType], assignment.RoleID) {
+				continue
+			}
+			granted[assignment.RoleID] = true
+			rv = append(rv, grant.NewGrant(resource, assignment.RoleID, principal))
+		}
+	}
+
+	return rv
+}
+
+// inOrganization reports whether a role assignment belongs to the organization. Assignments without an organization ID
+// are scoped to the organization the member was listed in.
+func inOrganization(assignmentOrgID, orgID string) bool {
+	return assignmentOrgID == "" || assignmentOrgID == orgID
+}
+
+func isCloudRole(roles []cloudRole, id string) bool {
+	for _, role := range roles {
+		if role.id == id {
+			return true
+		}
+	}
+	return false
+}
+
+// Grant assigns an Elastic Cloud role to an organization member. Deployment and project roles granted on the
+// organization apply to all of its deployments and all of its projects of the type. Granting membership to a user or an invitation invites its email to the organization.
+func (r *organizationBuilder) Grant(ctx context.Context, principal *v2.Resource, entitlement *v2.Entitlement) (annotations.Annotations, error) {
+	l := ctxzap.Extract(ctx)
+
+	if entitlementSlug(entitlement) == orgMembership {
+		return r.invite(ctx, principal, entitlement.Resource.Id.Resource)
+	}
+
+	if principal.Id.ResourceType != userResourceType.Id {
+		l.Warn(
+			"baton-elastic: only users can be granted organization roles",
+			zap.String("principal_type", principal.Id.ResourceType),
+			zap.String("principal_id", principal.Id.Resource),
+		)
+		return nil, fmt.Errorf("baton-elastic: only users can be granted organization roles")
+	}
+
+	orgID := entitlement.Resource.Id.Resource
+	roleID := entitlementSlug(entitlement)
+
+	var assignments elastic.RoleAssignments
+	switch {
+	case isCloudRole(organizationRoles, roleID):
+		assignments.Organization = []elastic.OrganizationRoleAssignment{{OrganizationID: orgID, RoleID: roleID}}
+	case isCloudRole(deploymentRoles, roleID):
+		assignments.Deployment = []elastic.DeploymentRoleAssignment{{OrganizationID: orgID, RoleID: roleID, All: true}}
+	default:
+		projectType, ok := projectRoleType(roleID)
+		if !ok {
+			return nil, fmt.Errorf("baton-elastic: granting %s is not supported", roleID)
+		}
+		assignments.Project = elastic.NewProjectRoleAssignments(projectType, elastic.ProjectRoleAssignment{OrganizationID: orgID, RoleID: roleID, All: true})
+	}
+
+	err := r.client.AddRoleAssignments(ctx, principal.Id.Resource, assignments)
+	if err != nil {
+		return nil, fmt.Errorf("baton-elastic: failed to grant organization role to user: %w", err)
+	}
+
+	return nil, nil
+}
+
+// Revoke removes an Elastic Cloud role from an organization member. Revoking a deployment or project role only removes
+// its assignment for all deployments or projects, assignments for individual deployments and projects are revoked on
+// their resources.
+// Revoking membership removes the member from the organization, or cancels the invitation. Revoking a role of an
+// API key deletes the key.
+func (r *organizationBuilder) Revoke(ctx context.Context, grant *v2.Grant) (annotations.Annotations, error) {
+	l := ctxzap.Extract(ctx)
+	principal := grant.Principal
+	entitlement := grant.Entitlement
+
+	if entitlementSlug(entitlement) == orgMembership {
+		return r.removeMembership(ctx, principal, entitlement.Resource.Id.Resource)
+	}
+
+	if principal.Id.ResourceType == cloudAPIKeyResourceType.Id {
+		return deleteCloudAPIKey(ctx, r.client, principal)
+	}
+
+	if principal.Id.ResourceType != userResourceType.Id {
+		l.Warn(
+			"baton-elastic: only users can have organization roles revoked",
+			zap.String("principal_type", principal.Id.ResourceType),
+			zap.String("principal_id", principal.Id.Resource),
+		)
+		return nil, fmt.Errorf("baton-elastic: only users can have organization roles revoked")
+	}
+
+	orgID := entitlement.Resource.Id.Resource
+	roleID := entitlementSlug(entitlement)
+
+	var assignments elastic.RoleAssignments
+	switch {
+	case isCloudRole(organizationRoles, roleID):
+		assignments.Organization = []elastic.OrganizationRoleAssignment{{OrganizationID: orgID, RoleID: roleID}}
+	case isCloudRole(deploymentRoles, roleID):
+		member, err := getOrgMember(ctx, r.client, orgID, principal.Id.Resource)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, assignment := range member.RoleAssignments.Deployment {
+			if assignment.All && assignment.RoleID == roleID && inOrganization(assignment.OrganizationID, orgID) {
+				assignment.OrganizationID = orgID
+				assignments.Deployment = append(assignments.Deployment, assignment)
+			}
+		}
+	default:
+		projectType, ok := projectRoleType(roleID)
+		if !ok {
+			return nil, fmt.Errorf("baton-elastic: revoking %s is not supported", roleID)
+		}
+
+		member, err := getOrgMember(ctx, r.client, orgID, principal.Id.Resource)
+		if err != nil {
+			return nil, err
+		}
+
+		var projectAssignments []elastic.ProjectRoleAssignment
+		for _, assignment := range member.RoleAssignments.Project.ForType(projectType) {
+			if assignment.All && assignment.RoleID == roleID && inOrganization(assignment.OrganizationID, orgID) {
+				assignment.OrganizationID = orgID
+				projectAssignments = append(projectAssignments, assignment)
+			}
+		}
+		if len(projectAssignments) > 0 {
+			assignments.Project = elastic.NewProjectRoleAssignments(projectType, projectAssignments...)
+		}
+	}
+
+	if len(assignments.Organization) == 0 && len(assignments.Deployment) == 0 && assignments.Project == nil {
+		l.Warn(
+			"baton-elastic: user does not have this organization role",
+			zap.String("principal_id", principal.Id.Resource),
+			zap.String("role", roleID),
+		)
+		return nil, nil
+	}
+
+	err := r.client.RemoveRoleAssignments(ctx, principal.Id.Resource, assignments)
+	if err != nil {
+		return nil, fmt.Errorf("baton-elastic: failed to revoke organization role from user: %w", err)
+	}
+
+	return nil, nil
+}
+
+// invite invites the email of the principal to the organization: the email of a user, e.g. a member of another
+// organization, or the invited email of an invitation, e.g. to send it again after it was canceled. Invitations are
+// sent without roles, roles are granted once the invitation is accepted. Emails with neither resource would be invited
+// through account provisioning, which baton-sdk v0.1.14 does not offer.
+func (r *organizationBuilder) invite(ctx context.Context, principal *v2.Resource, orgID string) (annotations.Annotations, error) {
+	l := ctxzap.Extract(ctx)
+
+	var email string
+	switch principal.Id.ResourceType {
+	case userResourceType.Id:
+		members, err := r.client.ListOrgMembers(ctx, orgID)
+		if err != nil {
+			return nil, fmt.Errorf("error listing organization members: %w", err)
+		}
+
+		for _, member := range members {
+			if member.UserID == principal.Id.Resource {
+				l.Warn(
+					"baton-elastic: user is already a member of the organization",
+					zap.String("principal_id", principal.Id.Resource),
+					zap.String("organization_id", orgID),
+				)
+				return nil, nil
+			}
+		}
+
+		email, err = userEmail(principal)
+		if err != nil {
+			return nil, err
+		}
+	case invitationResourceType.Id:
+		var err error
+		email, err = invitationEmail(principal)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		l.Warn(
+			"baton-elastic: only users and invitations can be granted organization membership",
+			zap.String("principal_type", principal.Id.ResourceType),
+			zap.String("principal_id", principal.Id.Resource),
+		)
+		return nil, fmt.Errorf("baton-elastic: only users and invitations can be granted organization membership")
+	}
+
+	invitations, err := listPendingInvitations(ctx, r.client, orgID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, invitation := range invitations {
+		if strings.EqualFold(invitation.Email, email) {
+			l.Warn(
+				"baton-elastic: email is already invited to the organization",
+				zap.String("principal_id", principal.Id.Resource),
+				zap.String("organization_id", orgID),
+			)
+			return nil, nil
+		}
+	}
+
+	if err := r.client.CreateOrgInvitation(ctx, orgID, email); err != nil {
+		return nil, fmt.Errorf("baton-elastic: failed to invite %s to organization: %w", email, err)
+	}
+
+	return nil, nil
+}
+
+// userEmail returns the primary email of the user resource.
+func userEmail(user *v2.Resource) (string, error) {
+	userTrait, err := rs.GetUserTrait(user)
+	if err != nil {
+		return "", err
+	}
+
+	for _, email := range userTrait.Emails {
+		if email.IsPrimary && email.Address != "" {
+			return email.Address, nil
+		}
+	}
+
+	return "", fmt.Errorf("baton-elastic: user %s has no email to invite", user.Id.Resource)
+}
+
+// removeMembership removes the user principal from the organization, unless it is the last member with the
+// organization admin role, or cancels the pending invitations of the invitation principal.
+func (r *organizationBuilder) removeMembership(ctx context.Context, principal *v2.Resource, orgID string) (annotations.Annotations, error) {
+	l := ctxzap.Extract(ctx)
+
+	switch principal.Id.ResourceType {
+	case userResourceType.Id:
+		members, err := r.client.ListOrgMembers(ctx, orgID)
+		if err != nil {
+			return nil, fmt.Errorf("error listing organization members: %w", err)
+		}
+
+		isMember, isLastAdmin := isLastOrganizationAdmin(members, orgID, principal.Id.Resource)
+		if !isMember {
+			l.Warn(
+				"baton-elastic: user is not a member of the organization",
+				zap.String("principal_id", principal.Id.Resource),
+				zap.String("organization_id", orgID),
+			)
+			return nil, nil
+		}
+
+		if isLastAdmin {
+			return nil, fmt.Errorf("baton-elastic: user %s is the last %s of organization %s and cannot be removed", principal.Id.Resource, organizationAdminRole, orgID)
+		}
+
+		if err := r.client.RemoveOrgMember(ctx, orgID, principal.Id.Resource); err != nil {
+			return nil, fmt.Errorf("baton-elastic: failed to remove user from organization: %w", err)
+		}
+	case invitationResourceType.Id:
+		email, err := invitationEmail(principal)
+		if err != nil {
+			return nil, err
+		}
+
+		invitations, err := listPendingInvitations(ctx, r.client, orgID)
+		if err != nil {
+			return nil, err
+		}
+
+		canceled := false
+		for _, invitation := range invitations {
+			if !strings.EqualFold(invitation.Email, email) {
+				continue
+			}
+			if err := r.client.DeleteOrgInvitation(ctx, orgID, invitation.Token); err != nil {
+				return nil, fmt.Errorf("baton-elastic: failed to cancel organization invitation: %w", err)
+			}
+			canceled = true
+		}
+
+		if !canceled {
+			l.Warn(
+				"baton-elastic: email has no pending invitation to the organization",
+				zap.String("principal_id", principal.Id.Resource),
+				zap.String("organization_id", orgID),
+			)
+		}
+	default:
+		l.Warn(
+			"baton-elastic: only users and invitations can have organization membership revoked",
+			zap.String("principal_type", principal.Id.ResourceType),
+			zap.String("principal_id", principal.Id.Resource),
+		)
+		return nil, fmt.Errorf("baton-elastic: only users and invitations can have organization membership revoked")
+	}
+
+	return nil, nil
+}
+
+// isLastOrganizationAdmin reports whether the user is a member of the organization and whether they are its only
+// member with the organization admin role. Cloud API keys holding the role are not counted, as a key can't sign in to
+// Elastic Cloud and so can't stand in for an admin member.
+func isLastOrganizationAdmin(members []elastic.User, orgID, userID string) (bool, bool) {
+	isMember, isAdmin, admins := false, false, 0
+	for _, member := range members {
+		memberIsAdmin := hasOrganizationRole(member, orgID, organizationAdminRole)
+		if memberIsAdmin {
+			admins++
+		}
+		if member.UserID == userID {
+			isMember, isAdmin = true, memberIsAdmin
+		}
+	}
+
+	return isMember, isAdmin && admins == 1
+}
+
+// hasOrganizationRole reports whether the member holds the organization role in the organization.
+func hasOrganizationRole(member elastic.User, orgID, roleID string) bool {
+	for _, assignment := range member.RoleAssignments.Organization {
+		if assignment.RoleID == roleID && inOrganization(assignment.OrganizationID, orgID) {
+			return true
+		}
+	}
+	return false
+}
+
+// getOrgMember returns the organization member with the given user ID.
+func getOrgMember(ctx context.Context, client *elastic.Client, orgID, userID string) (*elastic.User, error) {
+	members, err := client.ListOrgMembers(ctx, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("error listing organization members: %w", err)
+	}
+
+	for _, member := range members {
+		if member.UserID == userID {
+			memberCopy := member
+			return &memberCopy, nil
+		}
+	}
+
+	return nil, fmt.Errorf("baton-elastic: user %s is not a member of organization %s", userID, orgID)
+}
+
+func newOrganizationBuilder(client *elastic.Client, shouldSync bool) *organizationBuilder {
 	return &organizationBuilder{
 		resourceType: organizationResourceType,
 		client:       client,
+		shouldSync:   shouldSync,
 	}
 }
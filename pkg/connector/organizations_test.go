@@ -0,0 +1,152 @@
+package connector
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/conductorone/baton-elastic/pkg/elastic"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsLastOrganizationAdmin(t *testing.T) {
+	member := func(userID string, roles ...elastic.OrganizationRoleAssignment) elastic.User {
+		return elastic.User{UserID: userID, RoleAssignments: elastic.RoleAssignments{Organization: roles}}
+	}
+	admin := elastic.OrganizationRoleAssignment{OrganizationID: "org1", RoleID: organizationAdminRole}
+	otherOrgAdmin := elastic.OrganizationRoleAssignment{OrganizationID: "org2", RoleID: organizationAdminRole}
+	billing := elastic.OrganizationRoleAssignment{OrganizationID: "org1", RoleID: "billing-admin"}
+
+	members := []elastic.User{member("u1", admin), member("u2", billing, otherOrgAdmin), member("u3")}
+
+	isMember, isLastAdmin := isLastOrganizationAdmin(members, "org1", "u1")
+	assert.True(t, isMember)
+	assert.True(t, isLastAdmin)
+
+	isMember, isLastAdmin = isLastOrganizationAdmin(members, "org1", "u2")
+	assert.True(t, isMember)
+	assert.False(t, isLastAdmin)
+
+	isMember, isLastAdmin = isLastOrganizationAdmin(members, "org1", "u4")
+	assert.False(t, isMember)
+	assert.False(t, isLastAdmin)
+
+	// Another admin remains, so either of them can be removed.
+	members = append(members, member("u5", elastic.OrganizationRoleAssignment{RoleID: organizationAdminRole}))
+	isMember, isLastAdmin = isLastOrganizationAdmin(members, "org1", "u1")
+	assert.True(t, isMember)
+	assert.False(t, isLastAdmin)
+}
+
+func TestOrganizationMembershipGrantRevoke(t *testing.T) {
+	server := newTestServer(t, map[string]testResponse{
+		"GET /api/v1/organizations/org1/members": {http.StatusOK, `{"members":[
+			{"user_id":"u1","email":"admin@example.com","role_assignments":{"organization":[{"organization_id":"org1","role_id":"organization-admin"}]}},
+			{"user_id":"u3","email":"member@example.com"}
+		]}`},
+		"GET /api/v1/organizations/org1/invitations": {http.StatusOK, `{"invitations":[
+			{"token":"t1","organization_id":"org1","email":"pending@example.com"},
+			{"token":"t2","organization_id":"org1","email":"expired@example.com","expired":true}
+		]}`},
+		"POST /api/v1/organizations/org1/invitations":      {http.StatusOK, `{"invitations":[]}`},
+		"DELETE /api/v1/organizations/org1/invitations/t1": {http.StatusOK, `{}`},
+		"DELETE /api/v1/organizations/org1/members/u3":     {http.StatusOK, `{}`},
+	})
+
+	ctx := context.Background()
+	builder := newOrganizationBuilder(elastic.NewClient(server.Client(), "", "", "key", "").WithBaseURL(server.URL), true)
+	organization, err := organizationResource(elastic.Organization{ID: "org1", Name: "Org"})
+	assert.Nil(t, err)
+	membership := resourceEntitlement(t, builder, organization, orgMembership)
+
+	newUser, err := userResource(&elastic.User{UserID: "u2", Email: "new@example.com"}, organization.Id)
+	assert.Nil(t, err)
+	_, err = builder.Grant(ctx, newUser, membership)
+	assert.Nil(t, err)
+
+	// Members and pending invitations are not invited again.
+	member, err := userResource(&elastic.User{UserID: "u3", Email: "member@example.com"}, organization.Id)
+	assert.Nil(t, err)
+	_, err = builder.Grant(ctx, member, membership)
+	assert.Nil(t, err)
+
+	pending, err := invitationResource(&elastic.Invitation{Email: "pending@example.com"}, organization.Id)
+	assert.Nil(t, err)
+	assert.Equal(t, "org1/pending@example.com", pending.Id.Resource)
+	_, err = builder.Grant(ctx, pending, membership)
+	assert.Nil(t, err)
+
+	_, err = builder.Revoke(ctx, &v2.Grant{Entitlement: membership, Principal: pending})
+	assert.Nil(t, err)
+	_, err = builder.Revoke(ctx, &v2.Grant{Entitlement: membership, Principal: member})
+	assert.Nil(t, err)
+
+	admin, err := userResource(&elastic.User{UserID: "u1", Email: "admin@example.com"}, organization.Id)
+	assert.Nil(t, err)
+	_, err = builder.Revoke(ctx, &v2.Grant{Entitlement: membership, Principal: admin})
+	assert.NotNil(t, err)
+
+	assert.Equal(t, []testRequest{
+		{method: http.MethodPost, path: "/api/v1/organizations/org1/invitations", body: `{"emails":["new@example.com"]}`},
+		{method: http.MethodDelete, path: "/api/v1/organizations/org1/invitations/t1"},
+		{method: http.MethodDelete, path: "/api/v1/organizations/org1/members/u3"},
+	}, server.changes())
+}
+
+func TestOrganizationRoleGrantRevoke(t *testing.T) {
+	server := newTestServer(t, map[string]testResponse{
+		"GET /api/v1/organizations/org1/members": {http.StatusOK, `{"members":[
+			{"user_id":"u1","role_assignments":{"deployment":[{"organization_id":"org1","role_id":"deployment-admin","all":true}]}},
+			{"user_id":"u2"}
+		]}`},
+		"POST /api/v1/users/u2/role_assignments":   {http.StatusOK, `{}`},
+		"DELETE /api/v1/users/u1/role_assignments": {http.StatusOK, `{}`},
+		"DELETE /api/v1/users/u2/role_assignments": {http.StatusOK, `{}`},
+	})
+
+	ctx := context.Background()
+	builder := newOrganizationBuilder(elastic.NewClient(server.Client(), "", "", "key", "").WithBaseURL(server.URL), true)
+	organization, err := organizationResource(elastic.Organization{ID: "org1", Name: "Org"})
+	assert.Nil(t, err)
+	admin, err := userResource(&elastic.User{UserID: "u1"}, organization.Id)
+	assert.Nil(t, err)
+	member, err := userResource(&elastic.User{UserID: "u2"}, organization.Id)
+	assert.Nil(t, err)
+
+	_, err = builder.Grant(ctx, member, resourceEntitlement(t, builder, organization, "billing-admin"))
+	assert.Nil(t, err)
+	_, err = builder.Revoke(ctx, &v2.Grant{Entitlement: resourceEntitlement(t, builder, organization, "billing-admin"), Principal: member})
+	assert.Nil(t, err)
+
+	// Deployment roles granted and revoked on the organization are assigned for all deployments.
+	_, err = builder.Grant(ctx, member, resourceEntitlement(t, builder, organization, "deployment-viewer"))
+	assert.Nil(t, err)
+	_, err = builder.Revoke(ctx, &v2.Grant{Entitlement: resourceEntitlement(t, builder, organization, "deployment-admin"), Principal: admin})
+	assert.Nil(t, err)
+	_, err = builder.Revoke(ctx, &v2.Grant{Entitlement: resourceEntitlement(t, builder, organization, "deployment-editor"), Principal: member})
+	assert.Nil(t, err)
+
+	assert.Equal(t, []testRequest{
+		{
+			method: http.MethodPost,
+			path:   "/api/v1/users/u2/role_assignments",
+			body:   `{"organization":[{"organization_id":"org1","role_id":"billing-admin"}]}`,
+		},
+		{
+			method: http.MethodDelete,
+			path:   "/api/v1/users/u2/role_assignments",
+			body:   `{"organization":[{"organization_id":"org1","role_id":"billing-admin"}]}`,
+		},
+		{
+			method: http.MethodPost,
+			path:   "/api/v1/users/u2/role_assignments",
+			body:   `{"deployment":[{"organization_id":"org1","role_id":"deployment-viewer","all":true}]}`,
+		},
+		{
+			method: http.MethodDelete,
+			path:   "/api/v1/users/u1/role_assignments",
+			body:   `{"deployment":[{"organization_id":"org1","role_id":"deployment-admin","all":true}]}`,
+		},
+	}, server.changes())
+}
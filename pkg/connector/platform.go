@@ -0,0 +1,212 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"slices"
+
+	"github.com/conductorone/baton-elastic/pkg/elastic"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	ent "github.com/conductorone/baton-sdk/pkg/types/entitlement"
+	grant "github.com/conductorone/baton-sdk/pkg/types/grant"
+	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+)
+
+// platformRoles are the roles of Elastic Cloud Enterprise users.
+// https://www.elastic.co/guide/en/cloud-enterprise/current/ece-users-and-roles.html
+var platformRoles = []cloudRole{
+	{id: "ece_platform_admin", displayName: "Platform admin", description: "Manage the %s Elastic Cloud Enterprise platform and all of its deployments"},
+	{id: "ece_platform_viewer", displayName: "Platform viewer", description: "View the %s Elastic Cloud Enterprise platform and all of its deployments"},
+	{id: "ece_deployment_manager", displayName: "Deployment manager", description: "Create and manage deployments on the %s Elastic Cloud Enterprise platform"},
+	{id: "ece_deployment_viewer", displayName: "Deployment viewer", description: "View deployments on the %s Elastic Cloud Enterprise platform"},
+}
+
+type platformBuilder struct {
+	resourceType *v2.ResourceType
+	client       *elastic.Client
+	cloudAPIURL  string
+	shouldSync   bool
+}
+
+func (p *platformBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
+	return p.resourceType
+}
+
+// Create a new connector resource for Elastic Cloud Enterprise installation, identified by the host of its API.
+func platformResource(cloudAPIURL string) (*v2.Resource, error) {
+	u, err := url.Parse(cloudAPIURL)
+	if err != nil {
+		return nil, err
+	}
+
+	ret, err := rs.NewResource(
+		u.Host,
+		platformResourceType,
+		u.Host,
+		rs.WithAnnotation(
+			&v2.ChildResourceType{ResourceTypeId: platformUserResourceType.Id},
+			&v2.ChildResourceType{ResourceTypeId: deploymentResourceType.Id},
+		))
+	if err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+// List returns the Elastic Cloud Enterprise installation the connector talks to.
+func (p *platformBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId, pToken *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	if !p.shouldSync {
+		return nil, "", nil, nil
+	}
+
+	pr, err := platformResource(p.cloudAPIURL)
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("error creating platform resource: %w", err)
+	}
+
+	return []*v2.Resource{pr}, "", nil, nil
+}
+
+func (p *platformBuilder) Entitlements(_ context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	var rv []*v2.Entitlement
+	for _, role := range platformRoles {
+		rv = append(rv, ent.NewAssignmentEntitlement(
+			resource,
+			role.id,
+			ent.WithGrantableTo(platformUserResourceType),
+			ent.WithDisplayName(fmt.Sprintf("%s %s", resource.DisplayName, role.displayName)),
+			ent.WithDescription(fmt.Sprintf(role.description, resource.DisplayName)),
+		))
+	}
+
+	return rv, "", nil, nil
+}
+
+func (p *platformBuilder) Grants(ctx context.Context, resource *v2.Resource, pToken *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	users, err := p.client.ListPlatformUsers(ctx)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	var rv []*v2.Grant
+	for _, user := range users {
+		userCopy := user
+		ur, err := platformUserResource(&userCopy, resource.Id)
+		if err != nil {
+			return nil, "", nil, fmt.Errorf("error creating platform user resource for platform %s: %w", resource.Id.Resource, err)
+		}
+
+		for _, role := range user.Security.Roles {
+			if isCloudRole(platformRoles, role) {
+				rv = append(rv, grant.NewGrant(resource, role, ur.Id))
+			}
+		}
+	}
+
+	return rv, "", nil, nil
+}
+
+func (p *platformBuilder) Grant(ctx context.Context, principal *v2.Resource, entitlement *v2.Entitlement) (annotations.Annotations, error) {
+	l := ctxzap.Extract(ctx)
+
+	if principal.Id.ResourceType != platformUserResourceType.Id {
+		l.Warn(
+			"baton-elastic: only platform users can be granted platform roles",
+			zap.String("principal_type", principal.Id.ResourceType),
+			zap.String("principal_id", principal.Id.Resource),
+		)
+		return nil, fmt.Errorf("baton-elastic: only platform users can be granted platform roles")
+	}
+
+	roleID := entitlementSlug(entitlement)
+	if !isCloudRole(platformRoles, roleID) {
+		return nil, fmt.Errorf("baton-elastic: granting %s is not supported", roleID)
+	}
+
+	user, err := p.client.GetPlatformUser(ctx, principal.Id.Resource)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching platform user: %w", err)
+	}
+
+	if user.Builtin {
+		return nil, fmt.Errorf("baton-elastic: the roles of builtin platform user %s can't be changed", user.UserName)
+	}
+
+	if slices.Contains(user.Security.Roles, roleID) {
+		l.Warn(
+			"baton-elastic: platform user already has this role",
+			zap.String("principal_id", principal.Id.Resource),
+			zap.String("role", roleID),
+		)
+		return nil, nil
+	}
+
+	err = p.client.UpdatePlatformUserRoles(ctx, user.UserName, append(user.Security.Roles, roleID))
+	if err != nil {
+		return nil, fmt.Errorf("baton-elastic: failed to grant platform role to user: %w", err)
+	}
+
+	return nil, nil
+}
+
+func (p *platformBuilder) Revoke(ctx context.Context, grant *v2.Grant) (annotations.Annotations, error) {
+	l := ctxzap.Extract(ctx)
+	principal := grant.Principal
+	entitlement := grant.Entitlement
+
+	if principal.Id.ResourceType != platformUserResourceType.Id {
+		l.Warn(
+			"baton-elastic: only platform users can have platform roles revoked",
+			zap.String("principal_type", principal.Id.ResourceType),
+			zap.String("principal_id", principal.Id.Resource),
+		)
+		return nil, fmt.Errorf("baton-elastic: only platform users can have platform roles revoked")
+	}
+
+	roleID := entitlementSlug(entitlement)
+	if !isCloudRole(platformRoles, roleID) {
+		return nil, fmt.Errorf("baton-elastic: revoking %s is not supported", roleID)
+	}
+
+	user, err := p.client.GetPlatformUser(ctx, principal.Id.Resource)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching platform user: %w", err)
+	}
+
+	if user.Builtin {
+		return nil, fmt.Errorf("baton-elastic: the roles of builtin platform user %s can't be changed", user.UserName)
+	}
+
+	if !slices.Contains(user.Security.Roles, roleID) {
+		l.Warn(
+			"baton-elastic: platform user does not have this role",
+			zap.String("principal_id", principal.Id.Resource),
+			zap.String("role", roleID),
+		)
+		return nil, nil
+	}
+
+	roles := slices.DeleteFunc(slices.Clone(user.Security.Roles), func(role string) bool { return role == roleID })
+
+	err = p.client.UpdatePlatformUserRoles(ctx, user.UserName, roles)
+	if err != nil {
+		return nil, fmt.Errorf("baton-elastic: failed to revoke platform role from user: %w", err)
+	}
+
+	return nil, nil
+}
+
+func newPlatformBuilder(client *elastic.Client, cloudAPIURL string, shouldSync bool) *platformBuilder {
+	return &platformBuilder{
+		resourceType: platformResourceType,
+		client:       client,
+		cloudAPIURL:  cloudAPIURL,
+		shouldSync:   shouldSync,
+	}
+}
@@ -0,0 +1,107 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/conductorone/baton-elastic/pkg/elastic"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/helpers"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
+)
+
+type platformUserBuilder struct {
+	resourceType *v2.ResourceType
+	client       *elastic.Client
+}
+
+func (p *platformUserBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
+	return p.resourceType
+}
+
+// Create a new connector resource for Elastic Cloud Enterprise user.
+func platformUserResource(user *elastic.PlatformUser, parentResourceID *v2.ResourceId) (*v2.Resource, error) {
+	firstname, lastname := helpers.SplitFullName(user.FullName)
+	profile := map[string]interface{}{
+		"first_name": firstname,
+		"last_name":  lastname,
+		"login":      user.UserName,
+		"user_id":    user.UserName,
+		"builtin":    user.Builtin,
+	}
+
+	status := v2.UserTrait_Status_STATUS_DISABLED
+	if user.Security.Enabled {
+		status = v2.UserTrait_Status_STATUS_ENABLED
+	}
+
+	userTraitOptions := []rs.UserTraitOption{
+		rs.WithUserProfile(profile),
+		rs.WithUserLogin(user.UserName),
+		rs.WithStatus(status),
+	}
+	if user.Email != "" {
+		userTraitOptions = append(userTraitOptions, rs.WithEmail(user.Email, true))
+	}
+
+	displayName := user.FullName
+	if displayName == "" {
+		displayName = user.UserName
+	}
+
+	ret, err := rs.NewUserResource(
+		displayName,
+		platformUserResourceType,
+		user.UserName,
+		userTraitOptions,
+		rs.WithParentResourceID(parentResourceID),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+// List returns all the users of the Elastic Cloud Enterprise installation.
+func (p *platformUserBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId, pToken *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	if parentResourceID == nil {
+		return nil, "", nil, nil
+	}
+
+	users, err := p.client.ListPlatformUsers(ctx)
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("error listing platform users: %w", err)
+	}
+
+	var rv []*v2.Resource
+	for _, user := range users {
+		userCopy := user
+		ur, err := platformUserResource(&userCopy, parentResourceID)
+		if err != nil {
+			return nil, "", nil, fmt.Errorf("error creating platform user resource: %w", err)
+		}
+		rv = append(rv, ur)
+	}
+
+	return rv, "", nil, nil
+}
+
+// Entitlements always returns an empty slice for users.
+func (p *platformUserBuilder) Entitlements(_ context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	return nil, "", nil, nil
+}
+
+// Grants always returns an empty slice for users since they don't have any entitlements.
+func (p *platformUserBuilder) Grants(ctx context.Context, resource *v2.Resource, pToken *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	return nil, "", nil, nil
+}
+
+func newPlatformUserBuilder(client *elastic.Client) *platformUserBuilder {
+	return &platformUserBuilder{
+		resourceType: platformUserResourceType,
+		client:       client,
+	}
+}
@@ -0,0 +1,55 @@
+package connector
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/conductorone/baton-elastic/pkg/elastic"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlatformRoleGrantRevoke(t *testing.T) {
+	server := newTestServer(t, map[string]testResponse{
+		"GET /api/v1/users/alice":   {http.StatusOK, `{"user_name":"alice","security":{"roles":["ece_deployment_viewer"]}}`},
+		"PATCH /api/v1/users/alice": {http.StatusOK, `{"user_name":"alice"}`},
+		"GET /api/v1/users/admin":   {http.StatusOK, `{"user_name":"admin","builtin":true,"security":{"roles":["ece_platform_admin"]}}`},
+	})
+
+	ctx := context.Background()
+	client := elastic.NewClient(server.Client(), "", "", "key", "").WithBaseURL(server.URL)
+	platform := newPlatformBuilder(client, "https://ece.example.com:12443", true)
+
+	resource, err := platformResource("https://ece.example.com:12443")
+	assert.Nil(t, err)
+	alice, err := platformUserResource(&elastic.PlatformUser{UserName: "alice"}, resource.Id)
+	assert.Nil(t, err)
+	admin, err := platformUserResource(&elastic.PlatformUser{UserName: "admin", Builtin: true}, resource.Id)
+	assert.Nil(t, err)
+
+	// The roles of the user are replaced with the granted role added or the revoked role removed.
+	_, err = platform.Grant(ctx, alice, resourceEntitlement(t, platform, resource, "ece_platform_viewer"))
+	assert.Nil(t, err)
+	_, err = platform.Revoke(ctx, &v2.Grant{Entitlement: resourceEntitlement(t, platform, resource, "ece_deployment_viewer"), Principal: alice})
+	assert.Nil(t, err)
+
+	// The roles of builtin users can't be changed.
+	_, err = platform.Grant(ctx, admin, resourceEntitlement(t, platform, resource, "ece_platform_viewer"))
+	assert.NotNil(t, err)
+	_, err = platform.Revoke(ctx, &v2.Grant{Entitlement: resourceEntitlement(t, platform, resource, "ece_platform_admin"), Principal: admin})
+	assert.NotNil(t, err)
+
+	assert.Equal(t, []testRequest{
+		{
+			method: http.MethodPatch,
+			path:   "/api/v1/users/alice",
+			body:   `{"security":{"roles":["ece_deployment_viewer","ece_platform_viewer"]}}`,
+		},
+		{
+			method: http.MethodPatch,
+			path:   "/api/v1/users/alice",
+			body:   `{"security":{"roles":[]}}`,
+		},
+	}, server.changes())
+}
@@ -0,0 +1,340 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/conductorone/baton-elastic/pkg/elastic"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	ent "github.com/conductorone/baton-sdk/pkg/types/entitlement"
+	grant "github.com/conductorone/baton-sdk/pkg/types/grant"
+	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+)
+
+// projectRoles are the predefined Elastic Cloud roles of serverless projects, by project type.
+// https://www.elastic.co/docs/deploy-manage/users-roles/cloud-organization/user-roles
+var projectRoles = map[string][]cloudRole{
+	elastic.ProjectTypeElasticsearch: {
+		{id: "elasticsearch-admin", displayName: "Admin", description: "Manage the %s Elasticsearch project"},
+		{id: "elasticsearch-developer", displayName: "Developer", description: "Develop on the %s Elasticsearch project"},
+		{id: "elasticsearch-viewer", displayName: "Viewer", description: "View the %s Elasticsearch project"},
+	},
+	elastic.ProjectTypeObservability: {
+		{id: "observability-admin", displayName: "Admin", description: "Manage the %s Observability project"},
+		{id: "observability-editor", displayName: "Editor", description: "Edit the %s Observability project"},
+		{id: "observability-viewer", displayName: "Viewer", description: "View the %s Observability project"},
+	},
+	elastic.ProjectTypeSecurity: {
+		{id: "security-admin", displayName: "Admin", description: "Manage the %s Security project"},
+		{id: "security-editor", displayName: "Editor", description: "Edit the %s Security project"},
+		{id: "security-viewer", displayName: "Viewer", description: "View the %s Security project"},
+		{id: "security-t1-analyst", displayName: "Tier 1 analyst", description: "Triage alerts in the %s Security project"},
+		{id: "security-t2-analyst", displayName: "Tier 2 analyst", description: "Investigate alerts in the %s Security project"},
+		{id: "security-t3-analyst", displayName: "Tier 3 analyst", description: "Hunt threats and respond to incidents in the %s Security project"},
+		{id: "security-threat-intel-analyst", displayName: "Threat intelligence analyst", description: "Manage threat intelligence in the %s Security project"},
+		{id: "security-rule-author", displayName: "Rule author", description: "Author detection rules in the %s Security project"},
+		{id: "security-soc-manager", displayName: "SOC manager", description: "Manage the security operations of the %s Security project"},
+		{id: "security-endpoint-operations-analyst", displayName: "Endpoint operations analyst", description: "Operate endpoints of the %s Security project"},
+		{id: "security-endpoint-policy-manager", displayName: "Endpoint policy manager", description: "Manage endpoint policies of the %s Security project"},
+		{id: "security-detections-admin", displayName: "Detections admin", description: "Manage detection rules and alerts of the %s Security project"},
+		{id: "security-platform-engineer", displayName: "Platform engineer", description: "Manage the data and integrations of the %s Security project"},
+	},
+}
+
+// projectTypeNames are the display names of the serverless project types.
+var projectTypeNames = map[string]string{
+	elastic.ProjectTypeElasticsearch: "Elasticsearch",
+	elastic.ProjectTypeObservability: "Observability",
+	elastic.ProjectTypeSecurity:      "Security",
+}
+
+type projectBuilder struct {
+	resourceType  *v2.ResourceType
+	client        *elastic.Client
+	organizations *organizationCache
+}
+
+func (p *projectBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
+	return p.resourceType
+}
+
+// Create a new connector resource for Elastic Cloud Serverless project.
+func projectResource(project *elastic.Project, parentResourceID *v2.ResourceId) (*v2.Resource, error) {
+	profile := map[string]interface{}{
+		"project_id":             project.ID,
+		"project_name":           project.Name,
+		"project_type":           project.Type,
+		"alias":                  project.Alias,
+		"region":                 project.RegionID,
+		"cloud_id":               project.CloudID,
+		"elasticsearch_endpoint": project.Endpoints.Elasticsearch,
+		"kibana_endpoint":        project.Endpoints.Kibana,
+	}
+
+	appTraitOptions := []rs.AppTraitOption{
+		rs.WithAppProfile(profile),
+	}
+
+	ret, err := rs.NewAppResource(
+		project.Name,
+		projectResourceType,
+		project.ID,
+		appTraitOptions,
+		rs.WithParentResourceID(parentResourceID),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+// List returns the serverless projects of the organization.
+func (p *projectBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId, pToken *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	if parentResourceID == nil || parentResourceID.ResourceType != organizationResourceType.Id {
+		return nil, "", nil, nil
+	}
+
+	projects, err := p.client.ListProjects(ctx)
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("error listing projects: %w", err)
+	}
+
+	var rv []*v2.Resource
+	for _, project := range projects {
+		if !inOrganization(project.Metadata.OrganizationID, parentResourceID.Resource) {
+			continue
+		}
+
+		projectCopy := project
+		pr, err := projectResource(&projectCopy, parentResourceID)
+		if err != nil {
+			return nil, "", nil, fmt.Errorf("error creating project resource for project %s: %w", project.ID, err)
+		}
+		rv = append(rv, pr)
+	}
+
+	return rv, "", nil, nil
+}
+
+// Entitlements returns the roles of the project type.
+func (p *projectBuilder) Entitlements(_ context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	projectType, err := projectTypeOf(resource)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	var rv []*v2.Entitlement
+	for _, role := range projectRoles[projectType] {
+		rv = append(rv, ent.NewAssignmentEntitlement(
+			resource,
+			role.id,
+			ent.WithGrantableTo(userResourceType, cloudAPIKeyResourceType),
+			ent.WithDisplayName(fmt.Sprintf("%s Project %s", resource.DisplayName, role.displayName)),
+			ent.WithDescription(fmt.Sprintf(role.description, resource.DisplayName)),
+		))
+	}
+
+	return rv, "", nil, nil
+}
+
+// Grants returns the project roles of organization members, including roles assigned for all projects of the type.
+func (p *projectBuilder) Grants(ctx context.Context, resource *v2.Resource, pToken *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	projectType, err := projectTypeOf(resource)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	orgID, err := projectOrganizationID(resource)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	members, err := p.organizations.listMembers(ctx, orgID)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	var rv []*v2.Grant
+	for _, member := range members {
+		memberCopy := member
+		ur, err := userResource(&memberCopy, resource.ParentResourceId)
+		if err != nil {
+			return nil, "", nil, fmt.Errorf("error creating user resource for project %s: %w", resource.Id.Resource, err)
+		}
+		rv = append(rv, projectRoleGrants(resource, projectType, orgID, ur.Id, member.RoleAssignments)...)
+	}
+
+	keys, err := p.organizations.listCloudAPIKeys(ctx, orgID)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	for _, key := range keys {
+		keyCopy := key
+		rv = append(rv, projectRoleGrants(resource, projectType, orgID, cloudAPIKeyID(&keyCopy), key.RoleAssignments)...)
+	}
+
+	return rv, "", nil, nil
+}
+
+// projectRoleGrants returns a grant for every project role the principal holds for the project.
+func projectRoleGrants(resource *v2.Resource, projectType, orgID string, principal *v2.ResourceId, assignments elastic.RoleAssignments) []*v2.Grant {
+	var rv []*v2.Grant
+	granted := make(map[string]bool)
+	for _, assignment := range assignments.Project.ForType(projectType) {
+		if granted[assignment.RoleID] || !isCloudRole(projectRoles[projectType], assignment.RoleID) || !inOrganization(assignment.OrganizationID, orgID) {
+			continue
+		}
+		if !assignment.All && !slices.Contains(assignment.ProjectIDs, resource.Id.Resource) {
+			continue
+		}
+		granted[assignment.RoleID] = true
+		rv = append(rv, grant.NewGrant(resource, assignment.RoleID, principal))
+	}
+
+	return rv
+}
+
+// Grant assigns a project role to an organization member for a single project.
+func (p *projectBuilder) Grant(ctx context.Context, principal *v2.Resource, entitlement *v2.Entitlement) (annotations.Annotations, error) {
+	l := ctxzap.Extract(ctx)
+
+	if principal.Id.ResourceType != userResourceType.Id {
+		l.Warn(
+			"baton-elastic: only users can be granted project roles",
+			zap.String("principal_type", principal.Id.ResourceType),
+			zap.String("principal_id", principal.Id.Resource),
+		)
+		return nil, fmt.Errorf("baton-elastic: only users can be granted project roles")
+	}
+
+	orgID, err := projectOrganizationID(entitlement.Resource)
+	if err != nil {
+		return nil, err
+	}
+
+	roleID := entitlementSlug(entitlement)
+	projectType, ok := projectRoleType(roleID)
+	if !ok {
+		return nil, fmt.Errorf("baton-elastic: granting %s is not supported", roleID)
+	}
+
+	err = p.client.AddRoleAssignments(ctx, principal.Id.Resource, elastic.RoleAssignments{
+		Project: elastic.NewProjectRoleAssignments(projectType, elastic.ProjectRoleAssignment{
+			OrganizationID: orgID,
+			RoleID:         roleID,
+			ProjectIDs:     []string{entitlement.Resource.Id.Resource},
+		}),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("baton-elastic: failed to grant project role to user: %w", err)
+	}
+
+	return nil, nil
+}
+
+// Revoke removes a project role of an organization member for a single project. Roles assigned for all projects of
+// the type cannot be revoked for a single project, they are revoked on the organization. Revoking a role of an API
+// key deletes the key.
+func (p *projectBuilder) Revoke(ctx context.Context, grant *v2.Grant) (annotations.Annotations, error) {
+	l := ctxzap.Extract(ctx)
+	principal := grant.Principal
+	entitlement := grant.Entitlement
+
+	if principal.Id.ResourceType == cloudAPIKeyResourceType.Id {
+		return deleteCloudAPIKey(ctx, p.client, principal)
+	}
+
+	if principal.Id.ResourceType != userResourceType.Id {
+		l.Warn(
+			"baton-elastic: only users can have project roles revoked",
+			zap.String("principal_type", principal.Id.ResourceType),
+			zap.String("principal_id", principal.Id.Resource),
+		)
+		return nil, fmt.Errorf("baton-elastic: only users can have project roles revoked")
+	}
+
+	orgID, err := projectOrganizationID(entitlement.Resource)
+	if err != nil {
+		return nil, err
+	}
+
+	roleID := entitlementSlug(entitlement)
+	projectType, ok := projectRoleType(roleID)
+	if !ok {
+		return nil, fmt.Errorf("baton-elastic: revoking %s is not supported", roleID)
+	}
+
+	member, err := getOrgMember(ctx, p.client, orgID, principal.Id.Resource)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, assignment := range member.RoleAssignments.Project.ForType(projectType) {
+		if assignment.RoleID == roleID && assignment.All && inOrganization(assignment.OrganizationID, orgID) {
+			return nil, fmt.Errorf("baton-elastic: user %s has %s for all %s projects, it cannot be revoked for a single project but on the organization", principal.Id.Resource, roleID, projectType)
+		}
+	}
+
+	err = p.client.RemoveRoleAssignments(ctx, principal.Id.Resource, elastic.RoleAssignments{
+		Project: elastic.NewProjectRoleAssignments(projectType, elastic.ProjectRoleAssignment{
+			OrganizationID: orgID,
+			RoleID:         roleID,
+			ProjectIDs:     []string{entitlement.Resource.Id.Resource},
+		}),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("baton-elastic: failed to revoke project role from user: %w", err)
+	}
+
+	return nil, nil
+}
+
+// projectTypeOf returns the type of the project resource from its profile.
+func projectTypeOf(resource *v2.Resource) (string, error) {
+	appTrait, err := rs.GetAppTrait(resource)
+	if err != nil {
+		return "", err
+	}
+
+	projectType, ok := rs.GetProfileStringValue(appTrait.Profile, "project_type")
+	if !ok {
+		return "", fmt.Errorf("baton-elastic: project %s has no project type", resource.Id.Resource)
+	}
+
+	return projectType, nil
+}
+
+// projectRoleType returns the type of the projects the role can be assigned for.
+func projectRoleType(roleID string) (string, bool) {
+	for projectType, roles := range projectRoles {
+		if isCloudRole(roles, roleID) {
+			return projectType, true
+		}
+	}
+
+	return "", false
+}
+
+// projectOrganizationID returns the ID of the organization the project resource was listed under.
+func projectOrganizationID(resource *v2.Resource) (string, error) {
+	if resource.ParentResourceId == nil || resource.ParentResourceId.ResourceType != organizationResourceType.Id {
+		return "", fmt.Errorf("baton-elastic: project %s has no parent organization", resource.Id.Resource)
+	}
+
+	return resource.ParentResourceId.Resource, nil
+}
+
+func newProjectBuilder(client *elastic.Client, organizations *organizationCache) *projectBuilder {
+	return &projectBuilder{
+		resourceType:  projectResourceType,
+		client:        client,
+		organizations: organizations,
+	}
+}
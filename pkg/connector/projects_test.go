@@ -0,0 +1,86 @@
+package connector
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/conductorone/baton-elastic/pkg/elastic"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProjectRoleAssignments(t *testing.T) {
+	server := newTestServer(t, map[string]testResponse{
+		"GET /api/v1/organizations/org1/members": {http.StatusOK, `{"members":[
+			{"user_id":"u1","role_assignments":{"project":{"elasticsearch":[{"organization_id":"org1","role_id":"elasticsearch-admin","all":true}]}}},
+			{"user_id":"u2"}
+		]}`},
+		"POST /api/v1/users/u1/role_assignments":   {http.StatusOK, `{}`},
+		"DELETE /api/v1/users/u1/role_assignments": {http.StatusOK, `{}`},
+		"POST /api/v1/users/u2/role_assignments":   {http.StatusOK, `{}`},
+		"DELETE /api/v1/users/u2/role_assignments": {http.StatusOK, `{}`},
+	})
+
+	ctx := context.Background()
+	client := elastic.NewClient(server.Client(), "", "", "key", "").WithBaseURL(server.URL)
+	organizations := newOrganizationBuilder(client, true)
+	projects := newProjectBuilder(client, newOrganizationCache(client))
+
+	organization, err := organizationResource(elastic.Organization{ID: "org1", Name: "Org"})
+	assert.Nil(t, err)
+	search, err := projectResource(&elastic.Project{ID: "p1", Name: "Search", Type: elastic.ProjectTypeElasticsearch}, organization.Id)
+	assert.Nil(t, err)
+	logs, err := projectResource(&elastic.Project{ID: "p2", Name: "Logs", Type: elastic.ProjectTypeObservability}, organization.Id)
+	assert.Nil(t, err)
+	admin, err := userResource(&elastic.User{UserID: "u1"}, organization.Id)
+	assert.Nil(t, err)
+	developer, err := userResource(&elastic.User{UserID: "u2"}, organization.Id)
+	assert.Nil(t, err)
+
+	// Roles granted on a project are assigned for that project only, under its project type.
+	_, err = projects.Grant(ctx, developer, resourceEntitlement(t, projects, search, "elasticsearch-developer"))
+	assert.Nil(t, err)
+	_, err = projects.Grant(ctx, developer, resourceEntitlement(t, projects, logs, "observability-viewer"))
+	assert.Nil(t, err)
+	_, err = projects.Revoke(ctx, &v2.Grant{Entitlement: resourceEntitlement(t, projects, search, "elasticsearch-developer"), Principal: developer})
+	assert.Nil(t, err)
+
+	// Roles assigned for all projects can't be revoked for a single project.
+	_, err = projects.Revoke(ctx, &v2.Grant{Entitlement: resourceEntitlement(t, projects, search, "elasticsearch-admin"), Principal: admin})
+	assert.NotNil(t, err)
+
+	// Project roles granted and revoked on the organization are assigned for all projects of the type.
+	_, err = organizations.Grant(ctx, developer, resourceEntitlement(t, organizations, organization, "security-viewer"))
+	assert.Nil(t, err)
+	_, err = organizations.Revoke(ctx, &v2.Grant{Entitlement: resourceEntitlement(t, organizations, organization, "elasticsearch-admin"), Principal: admin})
+	assert.Nil(t, err)
+
+	assert.Equal(t, []testRequest{
+		{
+			method: http.MethodPost,
+			path:   "/api/v1/users/u2/role_assignments",
+			body:   `{"project":{"elasticsearch":[{"organization_id":"org1","role_id":"elasticsearch-developer","project_ids":["p1"]}]}}`,
+		},
+		{
+			method: http.MethodPost,
+			path:   "/api/v1/users/u2/role_assignments",
+			body:   `{"project":{"observability":[{"organization_id":"org1","role_id":"observability-viewer","project_ids":["p2"]}]}}`,
+		},
+		{
+			method: http.MethodDelete,
+			path:   "/api/v1/users/u2/role_assignments",
+			body:   `{"project":{"elasticsearch":[{"organization_id":"org1","role_id":"elasticsearch-developer","project_ids":["p1"]}]}}`,
+		},
+		{
+			method: http.MethodPost,
+			path:   "/api/v1/users/u2/role_assignments",
+			body:   `{"project":{"security":[{"organization_id":"org1","role_id":"security-viewer","all":true}]}}`,
+		},
+		{
+			method: http.MethodDelete,
+			path:   "/api/v1/users/u1/role_assignments",
+			body:   `{"project":{"elasticsearch":[{"organization_id":"org1","role_id":"elasticsearch-admin","all":true}]}}`,
+		},
+	}, server.changes())
+}
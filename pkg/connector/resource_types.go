@@ -16,6 +16,16 @@ var (
 		Id:          "deploymentUser",
 		DisplayName: "Deployment User",
 		Traits:      []v2.ResourceType_Trait{v2.ResourceType_TRAIT_USER},
+	}
+	cloudAPIKeyResourceType = &v2.ResourceType{
+		Id:          "cloudApiKey",
+		DisplayName: "Cloud API Key",
+		Traits:      []v2.ResourceType_Trait{v2.ResourceType_TRAIT_APP},
+	}
+	invitationResourceType = &v2.ResourceType{
+		Id:          "invitation",
+		DisplayName: "Invitation",
+		Traits:      []v2.ResourceType_Trait{v2.ResourceType_TRAIT_USER},
 		Annotations: annotationsForUserResourceType(),
 	}
 	deploymentRoleResourceType = &v2.ResourceType{
@@ -27,6 +37,60 @@ var (
 		Id:          "organization",
 		DisplayName: "Organization",
 	}
+	deploymentResourceType = &v2.ResourceType{
+		Id:          "deployment",
+		DisplayName: "Deployment",
+		Traits:      []v2.ResourceType_Trait{v2.ResourceType_TRAIT_APP},
+	}
+	projectResourceType = &v2.ResourceType{
+		Id:          "project",
+		DisplayName: "Serverless Project",
+		Traits:      []v2.ResourceType_Trait{v2.ResourceType_TRAIT_APP},
+	}
+	platformResourceType = &v2.ResourceType{
+		Id:          "platform",
+		DisplayName: "ECE Platform",
+	}
+	platformUserResourceType = &v2.ResourceType{
+		Id:          "platformUser",
+		DisplayName: "Platform User",
+		Traits:      []v2.ResourceType_Trait{v2.ResourceType_TRAIT_USER},
+		Annotations: annotationsForUserResourceType(),
+	}
+	deploymentAPIKeyResourceType = &v2.ResourceType{
+		Id:          "apiKey",
+		DisplayName: "Deployment API Key",
+		Traits:      []v2.ResourceType_Trait{v2.ResourceType_TRAIT_APP},
+	}
+	clusterResourceType = &v2.ResourceType{
+		Id:          "cluster",
+		DisplayName: "Cluster",
+	}
+	indexPatternResourceType = &v2.ResourceType{
+		Id:          "indexPattern",
+		DisplayName: "Index Pattern",
+	}
+	applicationResourceType = &v2.ResourceType{
+		Id:          "application",
+		DisplayName: "Application",
+	}
+	serviceAccountResourceType = &v2.ResourceType{
+		Id:          "serviceAccount",
+		DisplayName: "Service Account",
+		Traits:      []v2.ResourceType_Trait{v2.ResourceType_TRAIT_USER},
+		Annotations: annotationsForUserResourceType(),
+	}
+	serviceTokenResourceType = &v2.ResourceType{
+		Id:          "serviceToken",
+		DisplayName: "Service Token",
+		Traits:      []v2.ResourceType_Trait{v2.ResourceType_TRAIT_APP},
+	}
+	externalGroupResourceType = &v2.ResourceType{
+		Id:          "externalGroup",
+		DisplayName: "External Group",
+		Traits:      []v2.ResourceType_Trait{v2.ResourceType_TRAIT_GROUP},
+		Annotations: annotationsForUserResourceType(),
+	}
 	roleMappingResourceType = &v2.ResourceType{
 		Id:          "roleMapping",
 		DisplayName: "Role Mapping",
@@ -3,7 +3,9 @@ package connector
 import (
 	"context"
 	"fmt"
+	"reflect"
 	"slices"
+	"strings"
 
 	"github.com/conductorone/baton-elastic/pkg/elastic"
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
@@ -17,19 +19,16 @@ import (
 )
 
 type roleMappingBuilder struct {
-	resourceType         *v2.ResourceType
-	client               *elastic.Client
-	shouldSyncDeployment bool
+	resourceType *v2.ResourceType
+	deployments  *deploymentClients
 }
 
-const NF = -1
-
 func (r *roleMappingBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
 	return r.resourceType
 }
 
 // Create a new connector resource for role mapping.
-func roleMappingResource(role string) (*v2.Resource, error) {
+func roleMappingResource(deployment *deploymentClient, role string) (*v2.Resource, error) {
 	profile := map[string]interface{}{
 		"role_mapping_id":   role,
 		"role_mapping_name": role,
@@ -42,8 +41,9 @@ func roleMappingResource(role string) (*v2.Resource, error) {
 	ret, err := rs.NewRoleResource(
 		role,
 		roleMappingResourceType,
-		role,
+		deployment.resourceID(role),
 		roleOptions,
+		rs.WithParentResourceID(deployment.parentResourceID()),
 	)
 	if err != nil {
 		return nil, err
@@ -54,18 +54,22 @@ func roleMappingResource(role string) (*v2.Resource, error) {
 
 // List returns all the role mappings.
 func (r *roleMappingBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId, pToken *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
-	if !r.shouldSyncDeployment {
+	deployment, err := r.deployments.forParent(ctx, parentResourceID)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	if deployment == nil {
 		return nil, "", nil, nil
 	}
 
-	roles, err := r.client.ListDeploymentRoleMapping(ctx)
+	roles, err := deployment.client.ListDeploymentRoleMapping(ctx)
 	if err != nil {
 		return nil, "", nil, fmt.Errorf("error listing role mappings: %w", err)
 	}
 
 	var rv []*v2.Resource
 	for role := range roles {
-		ur, err := roleMappingResource(role)
+		ur, err := roleMappingResource(deployment, role)
 		if err != nil {
 			return nil, "", nil, fmt.Errorf("error creating role mapping resource %s: %w", role, err)
 		}
@@ -79,7 +83,7 @@ func (r *roleMappingBuilder) List(ctx context.Context, parentResourceID *v2.Reso
 func (r *roleMappingBuilder) Entitlements(ctx context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
 	var rv []*v2.Entitlement
 	assignmentOptions := []ent.EntitlementOption{
-		ent.WithGrantableTo(deploymentUserResourceType),
+		ent.WithGrantableTo(deploymentUserResourceType, externalGroupResourceType),
 		ent.WithDisplayName(fmt.Sprintf("%s Role %s", resource.DisplayName, roleMembership)),
 		ent.WithDescription(fmt.Sprintf("Member of %s elasticsearch role", resource.DisplayName)),
 	}
@@ -93,21 +97,21 @@ func (r *roleMappingBuilder) Entitlements(ctx context.Context, resource *v2.Reso
 	return rv, "", nil, nil
 }
 
-// GetRoleMappingUsers returns role mapping users.
-func (r *roleMappingBuilder) GetRoleMappingUsers(ctx context.Context, name string) ([]string, error) {
+// GetRoleMappingUsers returns users of the role mapping with the given resource ID.
+func (r *roleMappingBuilder) GetRoleMappingUsers(ctx context.Context, resourceID string) ([]string, error) {
 	var users []string
-	roles, err := r.client.GetDeploymentRoleMapping(ctx, name)
+	deployment, name, err := r.deployments.forResource(ctx, resourceID)
+	if err != nil {
+		return nil, err
+	}
+
+	roles, err := deployment.client.GetDeploymentRoleMapping(ctx, name)
 	if err != nil {
 		return nil, err
 	}
 
 	for _, role := range roles {
-		if field := role.Rules.(map[string]any)["field"]; field != nil {
-			userData := Utility{
-				Data: fmt.Sprintf("%s", field.(map[string]any)["username"]),
-			}
-			users = userData.TrimPrefix("[").TrimSuffix("]").Split(" ")
-		}
+		users = roleMappingFieldValues(role, "username")
 	}
 
 	return users, nil
@@ -116,31 +120,41 @@ func (r *roleMappingBuilder) GetRoleMappingUsers(ctx context.Context, name strin
 // Grants always returns an empty slice for users since they don't have any entitlements.
 func (r *roleMappingBuilder) Grants(ctx context.Context, resource *v2.Resource, pToken *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
 	var rv []*v2.Grant
-	roles, err := r.client.ListDeploymentRoleMapping(ctx)
+	deployment, name, err := r.deployments.forResource(ctx, resource.Id.Resource)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	roles, err := deployment.client.ListDeploymentRoleMapping(ctx)
 	if err != nil {
 		return nil, "", nil, err
 	}
 
 	for roleMappingName, role := range roles {
-		if roleMappingName != resource.Id.Resource {
+		if roleMappingName != name {
 			continue
 		}
 
-		if field := role.Rules.(map[string]any)["field"]; field != nil {
-			userData := Utility{
-				Data: fmt.Sprintf("%s", field.(map[string]any)["username"]),
+		for _, userName := range roleMappingFieldValues(role, "username") {
+			ur, err := deploymentUserResource(deployment, &elastic.DeploymentUser{
+				Username: userName,
+			})
+			if err != nil {
+				return nil, "", nil, fmt.Errorf("error creating role mapping resource for user %s: %w", resource.Id.Resource, err)
 			}
-			users := userData.TrimPrefix("[").TrimSuffix("]").Split(" ")
-			for _, userName := range users {
-				ur, err := deploymentUserResource(&elastic.DeploymentUser{
-					Username: userName,
-				})
+
+			gr := grant.NewGrant(resource, roleMembership, ur.Id)
+			rv = append(rv, gr)
+		}
+
+		for _, field := range externalGroupFields {
+			for _, value := range roleMappingFieldValues(role, field) {
+				er, err := externalGroupResource(deployment, field, value)
 				if err != nil {
-					return nil, "", nil, fmt.Errorf("error creating role mapping resource for user %s: %w", resource.Id.Resource, err)
+					return nil, "", nil, fmt.Errorf("error creating external group resource for %s %s of role mapping %s: %w", field, value, resource.Id.Resource, err)
 				}
 
-				gr := grant.NewGrant(resource, roleMembership, ur.Id)
-				rv = append(rv, gr)
+				rv = append(rv, grant.NewGrant(resource, roleMembership, er.Id))
 			}
 		}
 	}
@@ -148,130 +162,194 @@ func (r *roleMappingBuilder) Grants(ctx context.Context, resource *v2.Resource,
 	return rv, "", nil, nil
 }
 
+// Grant adds the username of the user, or the group or distinguished name of the external group, to the rules of the
+// role mapping, next to the values of the same field so that the conditions on them apply to it too. Mappings without
+// such a field rule are refused. Its roles, role templates, metadata, other rules and whether it is enabled are kept
+// as they are.
 func (r *roleMappingBuilder) Grant(ctx context.Context, principal *v2.Resource, entitlement *v2.Entitlement) (annotations.Annotations, error) {
 	l := ctxzap.Extract(ctx)
-	if principal.Id.ResourceType != deploymentUserResourceType.Id {
+	if principal.Id.ResourceType != deploymentUserResourceType.Id && principal.Id.ResourceType != externalGroupResourceType.Id {
 		l.Warn(
-			"baton-elastic: only users can be granted role mapping membership",
+			"baton-elastic: only users and external groups can be granted role mapping membership",
 			zap.String("principal_type", principal.Id.ResourceType),
 			zap.String("principal_id", principal.Id.Resource),
 		)
-		return nil, fmt.Errorf("baton-elastic: only users can be granted role mapping membership")
+		return nil, fmt.Errorf("baton-elastic: only users and external groups can be granted role mapping membership")
 	}
 
-	user, err := r.client.GetDeploymentUser(ctx, principal.Id.Resource)
+	deployment, roleMappingName, field, value, err := r.membership(ctx, principal, entitlement)
 	if err != nil {
-		return nil, fmt.Errorf("error fetching user: %w", err)
+		return nil, err
 	}
 
-	newUser := user[principal.Id.Resource]
-	roleMappingName := entitlement.Resource.Id.Resource
-	users, err := r.GetRoleMappingUsers(ctx, roleMappingName)
+	mapping, err := getRoleMapping(ctx, deployment, roleMappingName)
 	if err != nil {
 		return nil, err
 	}
 
-	userPos := slices.IndexFunc(users, func(c string) bool {
-		return c == newUser.Username
-	})
-	if userPos != NF {
+	if slices.Contains(roleMappingFieldValues(mapping, field), value) {
 		l.Warn(
-			"baton-elastic: user already has this role mapping",
+			"baton-elastic: principal already has this role mapping",
 			zap.String("principal_id", principal.Id.String()),
 			zap.String("principal_type", principal.Id.ResourceType),
 			zap.String("roleMappingName", roleMappingName),
 		)
-		return nil, fmt.Errorf("baton-elastic: user %s already has this role mapping", principal.DisplayName)
+		return nil, fmt.Errorf("baton-elastic: %s already has this role mapping", principal.DisplayName)
 	}
 
-	users = append(users, newUser.Username)
-	data := elastic.MappingRolesBody{
-		Roles:   newUser.Roles,
-		Enabled: true,
-		Rules: elastic.Rule{
-			Field: elastic.Field{
-				Username: users,
-			},
-		},
+	rules := elastic.RoleMappingRule{Field: elastic.NewRoleMappingFieldRule(field, value)}
+	if mapping.Rules != nil {
+		var ok bool
+		rules, ok = mapping.Rules.WithFieldValue(field, value)
+		if !ok {
+			return nil, fmt.Errorf("baton-elastic: role mapping %s has no %s rule to add %s to without lifting its other conditions", roleMappingName, field, principal.DisplayName)
+		}
 	}
-	err = r.client.UpdateUserMappingRole(ctx, data, roleMappingName)
+	mapping.Rules = &rules
+
+	err = deployment.client.PutDeploymentRoleMapping(ctx, roleMappingName, mapping)
 	if err != nil {
-		return nil, fmt.Errorf("baton-elastic: failed to grant role mapping to user: %w", err)
+		return nil, fmt.Errorf("baton-elastic: failed to grant role mapping to %s: %w", principal.DisplayName, err)
 	}
 
 	l.Warn("Role Mapping Membership has been created.",
 		zap.String("roleMappingName", roleMappingName),
-		zap.String("User", newUser.Username),
+		zap.String(field, value),
 	)
 
 	return nil, nil
 }
 
+// Revoke removes the username of the user, or the group or distinguished name of the external group, from the rules
+// of the role mapping. Its roles, role templates, metadata, other rules and whether it is enabled are kept as they are.
 func (r *roleMappingBuilder) Revoke(ctx context.Context, grant *v2.Grant) (annotations.Annotations, error) {
 	l := ctxzap.Extract(ctx)
 	principal := grant.Principal
 	entitlement := grant.Entitlement
-	if principal.Id.ResourceType != deploymentUserResourceType.Id {
+	if principal.Id.ResourceType != deploymentUserResourceType.Id && principal.Id.ResourceType != externalGroupResourceType.Id {
 		l.Warn(
-			"baton-elastic: only users can have role membership revoked",
+			"baton-elastic: only users and external groups can have role membership revoked",
 			zap.String("principal_type", principal.Id.ResourceType),
 			zap.String("principal_id", principal.Id.Resource),
 		)
-		return nil, fmt.Errorf("baton-elastic: only users can have role membership revoked")
+		return nil, fmt.Errorf("baton-elastic: only users and external groups can have role membership revoked")
 	}
 
-	user, err := r.client.GetDeploymentUser(ctx, principal.Id.Resource)
+	deployment, roleMappingName, field, value, err := r.membership(ctx, principal, entitlement)
 	if err != nil {
-		return nil, fmt.Errorf("error fetching user: %w", err)
+		return nil, err
 	}
 
-	newUser := user[principal.Id.Resource]
-	roleMappingName := entitlement.Resource.Id.Resource
-	users, err := r.GetRoleMappingUsers(ctx, roleMappingName)
+	mapping, err := getRoleMapping(ctx, deployment, roleMappingName)
 	if err != nil {
 		return nil, err
 	}
 
-	userPos := slices.IndexFunc(users, func(c string) bool {
-		return c == newUser.Username
-	})
-	if userPos == NF {
+	if !slices.Contains(roleMappingFieldValues(mapping, field), value) {
 		l.Warn(
-			"baton-elastic: user does not have this role mapping",
+			"baton-elastic: principal does not have this role mapping",
 			zap.String("principal_id", principal.Id.String()),
 			zap.String("principal_type", principal.Id.ResourceType),
 			zap.String("roleMappingName", roleMappingName),
 		)
-		return nil, fmt.Errorf("baton-elastic: user %s does not have this role mapping", principal.DisplayName)
+		return nil, fmt.Errorf("baton-elastic: %s does not have this role mapping", principal.DisplayName)
 	}
 
-	users = append(users[:userPos], users[userPos+1:]...)
-	data := elastic.MappingRolesBody{
-		Roles:   newUser.Roles,
-		Enabled: true,
-		Rules: elastic.Rule{
-			Field: elastic.Field{
-				Username: users,
-			},
-		},
+	rules, ok := mapping.Rules.WithoutFieldValue(field, value)
+	if !ok {
+		return nil, fmt.Errorf("baton-elastic: revoking %s would leave role mapping %s matching nobody, disable or delete the role mapping instead", principal.DisplayName, roleMappingName)
+	}
+	if reflect.DeepEqual(rules, *mapping.Rules) {
+		return nil, fmt.Errorf("baton-elastic: the rules of role mapping %s match %s in a way revoking can't change", roleMappingName, principal.DisplayName)
 	}
-	err = r.client.UpdateUserMappingRole(ctx, data, roleMappingName)
+	mapping.Rules = &rules
+
+	err = deployment.client.PutDeploymentRoleMapping(ctx, roleMappingName, mapping)
 	if err != nil {
-		return nil, fmt.Errorf("baton-elastic: failed to revoke role mapping to user: %w", err)
+		return nil, fmt.Errorf("baton-elastic: failed to revoke role mapping from %s: %w", principal.DisplayName, err)
 	}
 
 	l.Warn("Role Membership has been revoked.",
 		zap.String("role Mapping", roleMappingName),
-		zap.String("User", newUser.Username),
+		zap.String(field, value),
 	)
 
 	return nil, nil
 }
 
-func newRoleMappingBuilder(client *elastic.Client, shouldSyncDeployment bool) *roleMappingBuilder {
+// membership returns the deployment and role mapping name of a role mapping membership, and the rule field and value
+// that match the principal: the username of a user, or the group or distinguished name of an external group.
+func (r *roleMappingBuilder) membership(ctx context.Context, principal *v2.Resource, entitlement *v2.Entitlement) (*deploymentClient, string, string, string, error) {
+	deployment, roleMappingName, err := r.deployments.forResource(ctx, entitlement.Resource.Id.Resource)
+	if err != nil {
+		return nil, "", "", "", err
+	}
+
+	principalDeployment, name, err := r.deployments.forResource(ctx, principal.Id.Resource)
+	if err != nil {
+		return nil, "", "", "", err
+	}
+	if principalDeployment.id != deployment.id {
+		return nil, "", "", "", fmt.Errorf("baton-elastic: %s and role mapping %s belong to different deployments", principal.Id.Resource, entitlement.Resource.Id.Resource)
+	}
+
+	if principal.Id.ResourceType == externalGroupResourceType.Id {
+		field, value, err := splitExternalGroup(name)
+		if err != nil {
+			return nil, "", "", "", err
+		}
+		return deployment, roleMappingName, field, value, nil
+	}
+
+	return deployment, roleMappingName, "username", name, nil
+}
+
+// getRoleMapping returns the role mapping as it is stored, so that it can be written back with only its rules changed.
+func getRoleMapping(ctx context.Context, deployment *deploymentClient, name string) (elastic.MappingRolesResponse, error) {
+	mappings, err := deployment.client.GetDeploymentRoleMapping(ctx, name)
+	if err != nil {
+		return elastic.MappingRolesResponse{}, fmt.Errorf("baton-elastic: failed to get role mapping %s: %w", name, err)
+	}
+
+	mapping, ok := mappings[name]
+	if !ok {
+		return elastic.MappingRolesResponse{}, fmt.Errorf("baton-elastic: role mapping %s not found", name)
+	}
+
+	return mapping, nil
+}
+
+// roleMappingFieldValues returns the values the field rules of the role mapping grant it to, e.g. usernames, including
+// those nested in any and all rules. Values within except rules exclude principals from the mapping and are skipped,
+// as are wildcard and regular expression patterns, which don't name a single principal.
+func roleMappingFieldValues(mapping elastic.MappingRolesResponse, field string) []string {
+	if mapping.Rules == nil {
+		return nil
+	}
+
+	var rv []string
+	for _, value := range mapping.Rules.FieldValues(field) {
+		if isRoleMappingPattern(value) {
+			continue
+		}
+		rv = append(rv, value)
+	}
+
+	return rv
+}
+
+// isRoleMappingPattern reports whether a role mapping field value is a wildcard or regular expression pattern.
+func isRoleMappingPattern(value string) bool {
+	if len(value) >= 2 && strings.HasPrefix(value, "/") && strings.HasSuffix(value, "/") {
+		return true
+	}
+
+	return strings.ContainsAny(value, "*?")
+}
+
+func newRoleMappingBuilder(deployments *deploymentClients) *roleMappingBuilder {
 	return &roleMappingBuilder{
-		resourceType:         roleMappingResourceType,
-		client:               client,
-		shouldSyncDeployment: shouldSyncDeployment,
+		resourceType: roleMappingResourceType,
+		deployments:  deployments,
 	}
 }
@@ -0,0 +1,106 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/conductorone/baton-elastic/pkg/elastic"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
+)
+
+type serviceAccountBuilder struct {
+	resourceType *v2.ResourceType
+	deployments  *deploymentClients
+}
+
+func (s *serviceAccountBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
+	return s.resourceType
+}
+
+// Create a new connector resource for Elastic deployment service account. The service tokens of the account are
+// listed under it.
+func serviceAccountResource(deployment *deploymentClient, principal string, account *elastic.ServiceAccount) (*v2.Resource, error) {
+	profile := map[string]interface{}{
+		"service_account": principal,
+		"cluster":         strings.Join(account.RoleDescriptor.Cluster, ","),
+	}
+
+	userTraitOptions := []rs.UserTraitOption{
+		rs.WithUserProfile(profile),
+		rs.WithUserLogin(principal),
+		rs.WithStatus(v2.UserTrait_Status_STATUS_ENABLED),
+	}
+
+	ret, err := rs.NewUserResource(
+		principal,
+		serviceAccountResourceType,
+		deployment.resourceID(principal),
+		userTraitOptions,
+		rs.WithParentResourceID(deployment.parentResourceID()),
+		rs.WithAnnotation(&v2.ChildResourceType{ResourceTypeId: serviceTokenResourceType.Id}),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+// List returns all the service accounts of the deployment.
+func (s *serviceAccountBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId, pToken *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	deployment, err := s.deployments.forParent(ctx, parentResourceID)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	if deployment == nil {
+		return nil, "", nil, nil
+	}
+
+	accounts, err := deployment.client.ListServiceAccounts(ctx)
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("error listing service accounts: %w", err)
+	}
+
+	var rv []*v2.Resource
+	for principal := range accounts {
+		accountCopy := accounts[principal]
+		sr, err := serviceAccountResource(deployment, principal, &accountCopy)
+		if err != nil {
+			return nil, "", nil, fmt.Errorf("error creating service account resource for service account %s: %w", principal, err)
+		}
+		rv = append(rv, sr)
+	}
+
+	return rv, "", nil, nil
+}
+
+// Entitlements always returns an empty slice for service accounts.
+func (s *serviceAccountBuilder) Entitlements(_ context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	return nil, "", nil, nil
+}
+
+// Grants always returns an empty slice for service accounts since they don't have any entitlements.
+func (s *serviceAccountBuilder) Grants(ctx context.Context, resource *v2.Resource, pToken *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	return nil, "", nil, nil
+}
+
+// splitServiceAccount splits the principal of a service account into its namespace and service name.
+func splitServiceAccount(principal string) (string, string, error) {
+	namespace, service, ok := strings.Cut(principal, "/")
+	if !ok {
+		return "", "", fmt.Errorf("baton-elastic: invalid service account %s", principal)
+	}
+
+	return namespace, service, nil
+}
+
+func newServiceAccountBuilder(deployments *deploymentClients) *serviceAccountBuilder {
+	return &serviceAccountBuilder{
+		resourceType: serviceAccountResourceType,
+		deployments:  deployments,
+	}
+}
@@ -0,0 +1,201 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	ent "github.com/conductorone/baton-sdk/pkg/types/entitlement"
+	grant "github.com/conductorone/baton-sdk/pkg/types/grant"
+	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+)
+
+const (
+	serviceTokenCredential = "credential"
+
+	serviceTokenSourceIndex = "index"
+	serviceTokenSourceFile  = "file"
+)
+
+type serviceTokenBuilder struct {
+	resourceType *v2.ResourceType
+	deployments  *deploymentClients
+}
+
+func (s *serviceTokenBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
+	return s.resourceType
+}
+
+// Create a new connector resource for service token of Elastic deployment service account. Index-backed tokens are
+// stored in the security index, file-backed tokens in the service_tokens file of the listed nodes.
+func serviceTokenResource(deployment *deploymentClient, principal, name, source string, nodes []string, parentResourceID *v2.ResourceId) (*v2.Resource, error) {
+	profile := map[string]interface{}{
+		"token_name":      name,
+		"service_account": principal,
+		"source":          source,
+	}
+	if len(nodes) > 0 {
+		profile["nodes"] = strings.Join(nodes, ",")
+	}
+
+	ret, err := rs.NewAppResource(
+		name,
+		serviceTokenResourceType,
+		deployment.resourceID(principal+"/"+name),
+		[]rs.AppTraitOption{rs.WithAppProfile(profile)},
+		rs.WithParentResourceID(parentResourceID),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+// List returns the index-backed and file-backed tokens of the service account.
+func (s *serviceTokenBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId, pToken *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	if parentResourceID == nil || parentResourceID.ResourceType != serviceAccountResourceType.Id {
+		return nil, "", nil, nil
+	}
+
+	deployment, principal, err := s.deployments.forResource(ctx, parentResourceID.Resource)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	namespace, service, err := splitServiceAccount(principal)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	credentials, err := deployment.client.GetServiceAccountCredentials(ctx, namespace, service)
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("error listing service tokens of service account %s: %w", principal, err)
+	}
+
+	var rv []*v2.Resource
+	for name := range credentials.Tokens {
+		tr, err := serviceTokenResource(deployment, principal, name, serviceTokenSourceIndex, nil, parentResourceID)
+		if err != nil {
+			return nil, "", nil, fmt.Errorf("error creating service token resource for service token %s: %w", name, err)
+		}
+		rv = append(rv, tr)
+	}
+
+	for name, token := range credentials.NodesCredentials.FileTokens {
+		if _, ok := credentials.Tokens[name]; ok {
+			continue
+		}
+
+		tr, err := serviceTokenResource(deployment, principal, name, serviceTokenSourceFile, token.Nodes, parentResourceID)
+		if err != nil {
+			return nil, "", nil, fmt.Errorf("error creating service token resource for service token %s: %w", name, err)
+		}
+		rv = append(rv, tr)
+	}
+
+	return rv, "", nil, nil
+}
+
+func (s *serviceTokenBuilder) Entitlements(_ context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	return []*v2.Entitlement{
+		ent.NewAssignmentEntitlement(
+			resource,
+			serviceTokenCredential,
+			ent.WithGrantableTo(serviceAccountResourceType),
+			ent.WithDisplayName(fmt.Sprintf("%s Service Token %s", resource.DisplayName, serviceTokenCredential)),
+			ent.WithDescription(fmt.Sprintf("Authenticates as the service account with the %s service token", resource.DisplayName)),
+		),
+	}, "", nil, nil
+}
+
+// Grants returns the credential grant of the service account the token belongs to.
+func (s *serviceTokenBuilder) Grants(ctx context.Context, resource *v2.Resource, pToken *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	deployment, name, err := s.deployments.forResource(ctx, resource.Id.Resource)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	principal, _, err := splitServiceToken(name)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	return []*v2.Grant{
+		grant.NewGrant(resource, serviceTokenCredential, &v2.ResourceId{
+			ResourceType: serviceAccountResourceType.Id,
+			Resource:     deployment.resourceID(principal),
+		}),
+	}, "", nil, nil
+}
+
+func (s *serviceTokenBuilder) Grant(ctx context.Context, principal *v2.Resource, entitlement *v2.Entitlement) (annotations.Annotations, error) {
+	return nil, fmt.Errorf("baton-elastic: service tokens can only be created in elasticsearch")
+}
+
+// Revoke deletes an index-backed service token. File-backed tokens have to be removed from the service_tokens file
+// of every node.
+func (s *serviceTokenBuilder) Revoke(ctx context.Context, grant *v2.Grant) (annotations.Annotations, error) {
+	l := ctxzap.Extract(ctx)
+
+	deployment, name, err := s.deployments.forResource(ctx, grant.Entitlement.Resource.Id.Resource)
+	if err != nil {
+		return nil, err
+	}
+
+	principal, tokenName, err := splitServiceToken(name)
+	if err != nil {
+		return nil, err
+	}
+
+	namespace, service, err := splitServiceAccount(principal)
+	if err != nil {
+		return nil, err
+	}
+
+	credentials, err := deployment.client.GetServiceAccountCredentials(ctx, namespace, service)
+	if err != nil {
+		return nil, fmt.Errorf("error listing service tokens of service account %s: %w", principal, err)
+	}
+
+	if _, ok := credentials.Tokens[tokenName]; !ok {
+		if _, ok := credentials.NodesCredentials.FileTokens[tokenName]; ok {
+			return nil, fmt.Errorf("baton-elastic: service token %s of %s is file-backed and has to be removed with elasticsearch-service-tokens on every node", tokenName, principal)
+		}
+
+		l.Warn(
+			"baton-elastic: service token does not exist",
+			zap.String("service_account", principal),
+			zap.String("token_name", tokenName),
+		)
+		return nil, nil
+	}
+
+	if _, err := deployment.client.DeleteServiceToken(ctx, namespace, service, tokenName); err != nil {
+		return nil, fmt.Errorf("baton-elastic: failed to delete service token: %w", err)
+	}
+
+	return nil, nil
+}
+
+// splitServiceToken splits the name of a service token resource into the service account principal and token name.
+func splitServiceToken(name string) (string, string, error) {
+	i := strings.LastIndex(name, "/")
+	if i < 0 {
+		return "", "", fmt.Errorf("baton-elastic: invalid service token %s", name)
+	}
+
+	return name[:i], name[i+1:], nil
+}
+
+func newServiceTokenBuilder(deployments *deploymentClients) *serviceTokenBuilder {
+	return &serviceTokenBuilder{
+		resourceType: serviceTokenResourceType,
+		deployments:  deployments,
+	}
+}
@@ -0,0 +1,46 @@
+package connector
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/conductorone/baton-elastic/pkg/elastic"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServiceTokenRevoke(t *testing.T) {
+	server := newTestServer(t, map[string]testResponse{
+		"GET /_security/service/elastic/fleet-server/credential": {http.StatusOK, `{
+			"service_account":"elastic/fleet-server",
+			"count":2,
+			"tokens":{"indexed":{}},
+			"nodes_credentials":{"_nodes":{"total":1,"successful":1,"failed":0},"file_tokens":{"filed":{"nodes":["node1"]}}}
+		}`},
+		"DELETE /_security/service/elastic/fleet-server/credential/token/indexed": {http.StatusOK, `{"found":true}`},
+	})
+
+	ctx := context.Background()
+	deployment := &deploymentClient{
+		id:     "d1",
+		client: elastic.NewDeploymentClient(server.Client(), server.URL, elastic.DeploymentCredentials{APIKey: "key"}),
+	}
+	tokens := newServiceTokenBuilder(newDeploymentClients(nil, false, []*deploymentClient{deployment}))
+
+	revoke := func(name, source string) error {
+		token, err := serviceTokenResource(deployment, "elastic/fleet-server", name, source, nil, nil)
+		assert.Nil(t, err)
+		_, err = tokens.Revoke(ctx, &v2.Grant{Entitlement: resourceEntitlement(t, tokens, token, serviceTokenCredential)})
+		return err
+	}
+
+	assert.Nil(t, revoke("indexed", serviceTokenSourceIndex))
+	// File-backed tokens can't be deleted through the API, so they are refused rather than reported as revoked.
+	assert.NotNil(t, revoke("filed", serviceTokenSourceFile))
+	assert.Nil(t, revoke("deleted", serviceTokenSourceIndex))
+
+	assert.Equal(t, []testRequest{
+		{method: http.MethodDelete, path: "/_security/service/elastic/fleet-server/credential/token/indexed"},
+	}, server.changes())
+}
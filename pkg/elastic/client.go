@@ -3,30 +3,99 @@ package elastic
 import (
 	"bytes"
 	"context"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"net/url"
 	"strings"
 )
 
-const baseUrl = "https://api.elastic-cloud.com/"
+// DefaultBaseURL is the Elastic Cloud API. Elastic Cloud Enterprise installations serve the same API on their own host.
+const DefaultBaseURL = "https://api.elastic-cloud.com/"
 
 type Client struct {
-	httpClient         *http.Client
-	apiKey             string
-	organizationID     string
-	deploymentApiKey   string
-	deploymentEndpoint string
+	httpClient     *http.Client
+	baseUrl        string
+	apiKey         string
+	organizationID string
+	// deploymentAuthorization is the Authorization header sent to the deployment, empty when the deployment
+	// authenticates the client with its TLS certificate.
+	deploymentAuthorization string
+	deploymentEndpoint      string
+	deploymentProxy         bool
+}
+
+// DeploymentCredentials authenticate requests to an elasticsearch deployment. The first one set is used, in the order
+// API key, username and password, bearer token. Without any, the deployment has to authenticate the TLS client
+// certificate of the HTTP client (PKI realm).
+type DeploymentCredentials struct {
+	APIKey   string
+	Username string
+	Password string
+	// BearerToken is an access token issued by the _security/oauth2/token API.
+	BearerToken string
+}
+
+func (c DeploymentCredentials) authorization() string {
+	switch {
+	case c.APIKey != "":
+		return fmt.Sprintf("ApiKey %s", c.APIKey)
+	case c.Username != "":
+		return fmt.Sprintf("Basic %s", base64.StdEncoding.EncodeToString([]byte(c.Username+":"+c.Password)))
+	case c.BearerToken != "":
+		return fmt.Sprintf("Bearer %s", c.BearerToken)
+	default:
+		return ""
+	}
 }
 
 func NewClient(httpClient *http.Client, deploymentApiKey, deploymentEndpoint, apiKey, organizationID string) *Client {
 	return &Client{
-		httpClient:         httpClient,
-		apiKey:             apiKey,
-		organizationID:     organizationID,
-		deploymentApiKey:   deploymentApiKey,
-		deploymentEndpoint: deploymentEndpoint,
+		httpClient:              httpClient,
+		baseUrl:                 DefaultBaseURL,
+		apiKey:                  apiKey,
+		organizationID:          organizationID,
+		deploymentAuthorization: DeploymentCredentials{APIKey: deploymentApiKey}.authorization(),
+		deploymentEndpoint:      deploymentEndpoint,
+	}
+}
+
+// NewDeploymentClient returns a client of a single elasticsearch deployment, which is not managed through
+// the Elastic cloud API.
+func NewDeploymentClient(httpClient *http.Client, deploymentEndpoint string, credentials DeploymentCredentials) *Client {
+	return &Client{
+		httpClient:              httpClient,
+		deploymentAuthorization: credentials.authorization(),
+		deploymentEndpoint:      deploymentEndpoint,
+	}
+}
+
+// WithBaseURL returns a copy of the client that sends Elastic Cloud API requests to baseURL, e.g. the API of an
+// Elastic Cloud Enterprise installation.
+func (c *Client) WithBaseURL(baseURL string) *Client {
+	client := *c
+	client.baseUrl = baseURL
+
+	return &client
+}
+
+// DeploymentProxy returns a copy of the client that reaches the elasticsearch APIs of the given deployment through
+// the Elastic Cloud API proxy, authenticated with the Elastic Cloud API key.
+// https://www.elastic.co/guide/en/cloud/current/ec-api-console.html
+func (c *Client) DeploymentProxy(deploymentID, refID string) *Client {
+	proxyUrl, _ := url.JoinPath(c.baseUrl, "api/v1/deployments", deploymentID, "elasticsearch", refID, "proxy")
+
+	return &Client{
+		httpClient:              c.httpClient,
+		baseUrl:                 c.baseUrl,
+		apiKey:                  c.apiKey,
+		organizationID:          c.organizationID,
+		deploymentAuthorization: DeploymentCredentials{APIKey: c.apiKey}.authorization(),
+		deploymentEndpoint:      proxyUrl,
+		deploymentProxy:         true,
 	}
 }
 
@@ -36,7 +105,7 @@ func (c *Client) ListOrganizations(ctx context.Context) ([]Organization, error)
 		Organizations []Organization `json:"organizations"`
 	}
 
-	orgUrl, _ := url.JoinPath(baseUrl, "api/v1/organizations")
+	orgUrl, _ := url.JoinPath(c.baseUrl, "api/v1/organizations")
 	if err := c.doRequest(ctx, orgUrl, &res, http.MethodGet, nil); err != nil {
 		return nil, err
 	}
@@ -54,7 +123,7 @@ func (c *Client) ListOrgMembers(ctx context.Context, orgId string) ([]User, erro
 		orgId = c.organizationID
 	}
 
-	orgUrl, _ := url.JoinPath(baseUrl, "api/v1/organizations", orgId, "members")
+	orgUrl, _ := url.JoinPath(c.baseUrl, "api/v1/organizations", orgId, "members")
 	if err := c.doRequest(ctx, orgUrl, &res, http.MethodGet, nil); err != nil {
 		return nil, err
 	}
@@ -62,6 +131,274 @@ func (c *Client) ListOrgMembers(ctx context.Context, orgId string) ([]User, erro
 	return res.Members, nil
 }
 
+// RemoveOrgMember removes a member from the Elastic organization.
+// https://www.elastic.co/docs/api/doc/cloud/operation/operation-delete-organization-memberships
+func (c *Client) RemoveOrgMember(ctx context.Context, orgID, userID string) error {
+	memberUrl, _ := url.JoinPath(c.baseUrl, "api/v1/organizations", orgID, "members", userID)
+
+	var res any
+	if err := c.doRequest(ctx, memberUrl, &res, http.MethodDelete, nil); err != nil {
+		return fmt.Errorf("error removing organization member: %w", err)
+	}
+
+	return nil
+}
+
+// ListOrgInvitations returns the invitations to join the Elastic organization.
+// https://www.elastic.co/docs/api/doc/cloud/operation/operation-list-organization-invitations
+func (c *Client) ListOrgInvitations(ctx context.Context, orgID string) ([]Invitation, error) {
+	var res struct {
+		Invitations []Invitation `json:"invitations"`
+	}
+
+	invitationsUrl, _ := url.JoinPath(c.baseUrl, "api/v1/organizations", orgID, "invitations")
+	if err := c.doRequest(ctx, invitationsUrl, &res, http.MethodGet, nil); err != nil {
+		return nil, err
+	}
+
+	return res.Invitations, nil
+}
+
+// CreateOrgInvitation invites the email to join the Elastic organization.
+// https://www.elastic.co/docs/api/doc/cloud/operation/operation-create-organization-invitations
+func (c *Client) CreateOrgInvitation(ctx context.Context, orgID, email string) error {
+	requestBody, err := json.Marshal(struct {
+		Emails []string `json:"emails"`
+	}{
+		Emails: []string{email},
+	})
+	if err != nil {
+		return err
+	}
+
+	var res struct {
+		Invitations []Invitation `json:"invitations"`
+	}
+
+	invitationsUrl, _ := url.JoinPath(c.baseUrl, "api/v1/organizations", orgID, "invitations")
+	if err := c.doRequest(ctx, invitationsUrl, &res, http.MethodPost, requestBody); err != nil {
+		return fmt.Errorf("error creating organization invitation: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteOrgInvitation cancels an invitation to join the Elastic organization.
+// https://www.elastic.co/docs/api/doc/cloud/operation/operation-delete-organization-invitations
+func (c *Client) DeleteOrgInvitation(ctx context.Context, orgID, token string) error {
+	invitationUrl, _ := url.JoinPath(c.baseUrl, "api/v1/organizations", orgID, "invitations", token)
+
+	var res any
+	if err := c.doRequest(ctx, invitationUrl, &res, http.MethodDelete, nil); err != nil {
+		return fmt.Errorf("error deleting organization invitation: %w", err)
+	}
+
+	return nil
+}
+
+// ListDeployments returns all deployments of the Elastic organization the API key belongs to.
+// The listing endpoint omits the deployment plan, so every deployment is fetched individually.
+// https://www.elastic.co/docs/api/doc/cloud/operation/operation-list-deployments
+func (c *Client) ListDeployments(ctx context.Context) ([]Deployment, error) {
+	var res struct {
+		Deployments []struct {
+			ID string `json:"id"`
+		} `json:"deployments"`
+	}
+
+	deploymentsUrl, _ := url.JoinPath(c.baseUrl, "api/v1/deployments")
+	if err := c.doRequest(ctx, deploymentsUrl, &res, http.MethodGet, nil); err != nil {
+		return nil, err
+	}
+
+	var deployments []Deployment
+	for _, d := range res.Deployments {
+		deployment, err := c.GetDeployment(ctx, d.ID)
+		if err != nil {
+			return nil, err
+		}
+		deployments = append(deployments, *deployment)
+	}
+
+	return deployments, nil
+}
+
+// GetDeployment returns a single Elastic Cloud deployment.
+// https://www.elastic.co/docs/api/doc/cloud/operation/operation-get-deployment
+func (c *Client) GetDeployment(ctx context.Context, deploymentID string) (*Deployment, error) {
+	var res Deployment
+	deploymentUrl, _ := url.JoinPath(c.baseUrl, "api/v1/deployments", deploymentID)
+	if err := c.doRequest(ctx, deploymentUrl, &res, http.MethodGet, nil); err != nil {
+		return nil, err
+	}
+
+	return &res, nil
+}
+
+// ListProjects returns the serverless projects of every type of the Elastic organization the API key belongs to.
+// https://www.elastic.co/docs/api/doc/elastic-cloud-serverless/group/endpoint-elasticsearch-projects
+func (c *Client) ListProjects(ctx context.Context) ([]Project, error) {
+	var projects []Project
+	for _, projectType := range ProjectTypes {
+		typeProjects, err := c.listProjects(ctx, projectType)
+		if err != nil {
+			return nil, err
+		}
+		projects = append(projects, typeProjects...)
+	}
+
+	return projects, nil
+}
+
+func (c *Client) listProjects(ctx context.Context, projectType string) ([]Project, error) {
+	projectsUrl, _ := url.JoinPath(c.baseUrl, "api/v1/serverless/projects", projectType)
+
+	var projects []Project
+	nextPage := ""
+	for {
+		var res struct {
+			Items    []Project `json:"items"`
+			NextPage string    `json:"next_page"`
+		}
+
+		pageUrl := projectsUrl
+		if nextPage != "" {
+			pageUrl = fmt.Sprintf("%s?%s", projectsUrl, url.Values{"next_page": {nextPage}}.Encode())
+		}
+		if err := c.doRequest(ctx, pageUrl, &res, http.MethodGet, nil); err != nil {
+			return nil, fmt.Errorf("error listing %s projects: %w", projectType, err)
+		}
+
+		for _, project := range res.Items {
+			if project.Type == "" {
+				project.Type = projectType
+			}
+			projects = append(projects, project)
+		}
+
+		if res.NextPage == "" {
+			return projects, nil
+		}
+		nextPage = res.NextPage
+	}
+}
+
+// AddRoleAssignments assigns Elastic Cloud roles to a user.
+// https://www.elastic.co/docs/api/doc/cloud/operation/operation-add-role-assignments
+func (c *Client) AddRoleAssignments(ctx context.Context, userID string, assignments RoleAssignments) error {
+	assignmentsUrl, _ := url.JoinPath(c.baseUrl, "api/v1/users", userID, "role_assignments")
+	requestBody, err := json.Marshal(assignments)
+	if err != nil {
+		return err
+	}
+
+	var res any
+	if err := c.doRequest(ctx, assignmentsUrl, &res, http.MethodPost, requestBody); err != nil {
+		return fmt.Errorf("error adding role assignments: %w", err)
+	}
+
+	return nil
+}
+
+// RemoveRoleAssignments removes Elastic Cloud roles from a user.
+// https://www.elastic.co/docs/api/doc/cloud/operation/operation-remove-role-assignments
+func (c *Client) RemoveRoleAssignments(ctx context.Context, userID string, assignments RoleAssignments) error {
+	assignmentsUrl, _ := url.JoinPath(c.baseUrl, "api/v1/users", userID, "role_assignments")
+	requestBody, err := json.Marshal(assignments)
+	if err != nil {
+		return err
+	}
+
+	var res any
+	if err := c.doRequest(ctx, assignmentsUrl, &res, http.MethodDelete, requestBody); err != nil {
+		return fmt.Errorf("error removing role assignments: %w", err)
+	}
+
+	return nil
+}
+
+// ListCloudAPIKeys returns the Elastic Cloud API keys of all users of the organization the API key belongs to.
+// https://www.elastic.co/docs/api/doc/cloud/operation/operation-get-users-api-keys
+func (c *Client) ListCloudAPIKeys(ctx context.Context) ([]CloudAPIKey, error) {
+	var res struct {
+		Keys []CloudAPIKey `json:"keys"`
+	}
+
+	keysUrl, _ := url.JoinPath(c.baseUrl, "api/v1/users/auth/keys/_all")
+	if err := c.doRequest(ctx, keysUrl, &res, http.MethodGet, nil); err != nil {
+		return nil, err
+	}
+
+	return res.Keys, nil
+}
+
+// DeleteCloudAPIKey deletes an Elastic Cloud API key of a user.
+// https://www.elastic.co/docs/api/doc/cloud/operation/operation-delete-user-api-key
+func (c *Client) DeleteCloudAPIKey(ctx context.Context, userID, keyID string) error {
+	keyUrl, _ := url.JoinPath(c.baseUrl, "api/v1/users", userID, "auth/keys", keyID)
+
+	var res any
+	if err := c.doRequest(ctx, keyUrl, &res, http.MethodDelete, nil); err != nil {
+		return fmt.Errorf("error deleting API key: %w", err)
+	}
+
+	return nil
+}
+
+// ListPlatformUsers returns all users of an Elastic Cloud Enterprise installation.
+// https://www.elastic.co/guide/en/cloud-enterprise/current/get-users.html
+func (c *Client) ListPlatformUsers(ctx context.Context) ([]PlatformUser, error) {
+	var res struct {
+		Users []PlatformUser `json:"users"`
+	}
+
+	usersUrl, _ := url.JoinPath(c.baseUrl, "api/v1/users")
+	if err := c.doRequest(ctx, usersUrl, &res, http.MethodGet, nil); err != nil {
+		return nil, err
+	}
+
+	return res.Users, nil
+}
+
+// GetPlatformUser returns a single user of an Elastic Cloud Enterprise installation.
+// https://www.elastic.co/guide/en/cloud-enterprise/current/get-user.html
+func (c *Client) GetPlatformUser(ctx context.Context, userName string) (*PlatformUser, error) {
+	var res PlatformUser
+	userUrl, _ := url.JoinPath(c.baseUrl, "api/v1/users", userName)
+	if err := c.doRequest(ctx, userUrl, &res, http.MethodGet, nil); err != nil {
+		return nil, err
+	}
+
+	return &res, nil
+}
+
+// UpdatePlatformUserRoles replaces the platform roles of an Elastic Cloud Enterprise user.
+// https://www.elastic.co/guide/en/cloud-enterprise/current/update-user.html
+func (c *Client) UpdatePlatformUserRoles(ctx context.Context, userName string, roles []string) error {
+	var body struct {
+		Security struct {
+			Roles []string `json:"roles"`
+		} `json:"security"`
+	}
+	body.Security.Roles = roles
+	if body.Security.Roles == nil {
+		body.Security.Roles = []string{}
+	}
+
+	requestBody, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	var res PlatformUser
+	userUrl, _ := url.JoinPath(c.baseUrl, "api/v1/users", userName)
+	if err := c.doRequest(ctx, userUrl, &res, http.MethodPatch, requestBody); err != nil {
+		return fmt.Errorf("error updating platform user: %w", err)
+	}
+
+	return nil
+}
+
 // ListDeploymentUsers returns a list of all Elastic deployment users.
 func (c *Client) ListDeploymentUsers(ctx context.Context) (map[string]DeploymentUser, error) {
 	res := make(map[string]DeploymentUser)
@@ -85,6 +422,101 @@ func (c *Client) GetDeploymentUser(ctx context.Context, username string) (map[st
 	return res, nil
 }
 
+// ListDeploymentAPIKeys returns the API keys of all users of the deployment, including invalidated keys.
+// https://www.elastic.co/guide/en/elasticsearch/reference/current/security-api-get-api-key.html
+func (c *Client) ListDeploymentAPIKeys(ctx context.Context) ([]DeploymentAPIKey, error) {
+	var res struct {
+		APIKeys []DeploymentAPIKey `json:"api_keys"`
+	}
+
+	keysUrl, _ := url.JoinPath(c.deploymentEndpoint, "_security/api_key")
+	if err := c.doRequest(ctx, keysUrl, &res, http.MethodGet, nil); err != nil {
+		return nil, err
+	}
+
+	return res.APIKeys, nil
+}
+
+// InvalidateDeploymentAPIKey invalidates an API key of the deployment.
+// https://www.elastic.co/guide/en/elasticsearch/reference/current/security-api-invalidate-api-key.html
+func (c *Client) InvalidateDeploymentAPIKey(ctx context.Context, id string) (*InvalidateAPIKeyResponse, error) {
+	requestBody, err := json.Marshal(struct {
+		IDs []string `json:"ids"`
+	}{
+		IDs: []string{id},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var res InvalidateAPIKeyResponse
+	keysUrl, _ := url.JoinPath(c.deploymentEndpoint, "_security/api_key")
+	if err := c.doRequest(ctx, keysUrl, &res, http.MethodDelete, requestBody); err != nil {
+		return nil, fmt.Errorf("error invalidating API key: %w", err)
+	}
+
+	if res.ErrorCount > 0 {
+		return nil, fmt.Errorf("error invalidating API key %s", id)
+	}
+
+	return &res, nil
+}
+
+// ListServiceAccounts returns the service accounts of the deployment by their principal, e.g. elastic/fleet-server.
+// https://www.elastic.co/guide/en/elasticsearch/reference/current/security-api-get-service-accounts.html
+func (c *Client) ListServiceAccounts(ctx context.Context) (map[string]ServiceAccount, error) {
+	res := make(map[string]ServiceAccount)
+	serviceUrl, _ := url.JoinPath(c.deploymentEndpoint, "_security/service")
+	if err := c.doRequest(ctx, serviceUrl, &res, http.MethodGet, nil); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// GetServiceAccountCredentials returns the service tokens of the service account.
+// https://www.elastic.co/guide/en/elasticsearch/reference/current/security-api-get-service-credentials.html
+func (c *Client) GetServiceAccountCredentials(ctx context.Context, namespace, service string) (*ServiceAccountCredentials, error) {
+	var res ServiceAccountCredentials
+	credentialUrl, _ := url.JoinPath(c.deploymentEndpoint, "_security/service", namespace, service, "credential")
+	if err := c.doRequest(ctx, credentialUrl, &res, http.MethodGet, nil); err != nil {
+		return nil, err
+	}
+
+	return &res, nil
+}
+
+// DeleteServiceToken deletes an index-backed service token of the service account. It reports whether the token
+// was found.
+// https://www.elastic.co/guide/en/elasticsearch/reference/current/security-api-delete-service-token.html
+func (c *Client) DeleteServiceToken(ctx context.Context, namespace, service, name string) (bool, error) {
+	var res struct {
+		Found bool `json:"found"`
+	}
+
+	tokenUrl, _ := url.JoinPath(c.deploymentEndpoint, "_security/service", namespace, service, "credential/token", name)
+	if err := c.doRequest(ctx, tokenUrl, &res, http.MethodDelete, nil); err != nil {
+		if IsNotFound(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("error deleting service token: %w", err)
+	}
+
+	return res.Found, nil
+}
+
+// ListApplicationPrivileges returns the registered privileges of every application, by application and privilege name.
+// https://www.elastic.co/guide/en/elasticsearch/reference/current/security-api-get-privileges.html
+func (c *Client) ListApplicationPrivileges(ctx context.Context) (map[string]map[string]ApplicationPrivilege, error) {
+	res := make(map[string]map[string]ApplicationPrivilege)
+	privilegeUrl, _ := url.JoinPath(c.deploymentEndpoint, "_security/privilege")
+	if err := c.doRequest(ctx, privilegeUrl, &res, http.MethodGet, nil); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
 // ListDeploymentRoles returns a list of all Elastic roles on deployment.
 func (c *Client) ListDeploymentRoles(ctx context.Context) (map[string]DeploymentRole, error) {
 	res := make(map[string]DeploymentRole)
@@ -120,6 +552,28 @@ func (c *Client) GetDeploymentRoleMapping(ctx context.Context, name string) (map
 	return res, nil
 }
 
+// PutDeploymentRoleMapping replaces the role mapping with the given one.
+// https://www.elastic.co/guide/en/elasticsearch/reference/current/security-api-put-role-mapping.html
+func (c *Client) PutDeploymentRoleMapping(ctx context.Context, name string, mapping MappingRolesResponse) error {
+	roleMappingUrl, _ := url.JoinPath(c.deploymentEndpoint, "_security/role_mapping", name)
+	requestBody, err := json.Marshal(mapping)
+	if err != nil {
+		return err
+	}
+
+	var res struct {
+		RoleMapping struct {
+			Created bool `json:"created"`
+		} `json:"role_mapping"`
+	}
+
+	if err := c.doRequest(ctx, roleMappingUrl, &res, http.MethodPut, requestBody); err != nil {
+		return fmt.Errorf("error updating role mapping: %w", err)
+	}
+
+	return nil
+}
+
 func (c *Client) DeleteDeploymentRoleMapping(ctx context.Context, name string) error {
 	var res any
 	roleMappingUrl, _ := url.JoinPath(c.deploymentEndpoint, "_security/role_mapping", name)
@@ -237,7 +691,13 @@ func (c *Client) doRequest(ctx context.Context, url string, res interface{}, met
 	req.Header.Add("Accept", "application/json")
 	req.Header.Add("Content-Type", "application/json")
 	if c.deploymentEndpoint != "" && strings.Contains(url, c.deploymentEndpoint) {
-		req.Header.Add("Authorization", fmt.Sprintf("ApiKey %s", c.deploymentApiKey))
+		if c.deploymentAuthorization != "" {
+			req.Header.Add("Authorization", c.deploymentAuthorization)
+		}
+		if c.deploymentProxy {
+			// The Elastic Cloud proxy refuses requests to elasticsearch APIs without this header.
+			req.Header.Add("X-Management-Request", "true")
+		}
 	} else {
 		req.Header.Add("Authorization", fmt.Sprintf("ApiKey %s", c.apiKey))
 	}
@@ -248,9 +708,46 @@ func (c *Client) doRequest(ctx context.Context, url string, res interface{}, met
 
 	defer resp.Body.Close()
 
-	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+		return &RequestError{
+			Method:     method,
+			URL:        url,
+			StatusCode: resp.StatusCode,
+			Body:       strings.TrimSpace(string(body)),
+		}
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil && !errors.Is(err, io.EOF) {
 		return err
 	}
 
 	return nil
 }
+
+// maxErrorBodySize limits how much of an error response is kept in a RequestError.
+const maxErrorBodySize = 4096
+
+// RequestError is returned for responses with a status code outside of 2xx, with the body of the response.
+type RequestError struct {
+	Method     string
+	URL        string
+	StatusCode int
+	Body       string
+}
+
+func (e *RequestError) Error() string {
+	return fmt.Sprintf("%s %s: unexpected status %d: %s", e.Method, e.URL, e.StatusCode, e.Body)
+}
+
+// IsNotFound reports whether the request failed because the requested object does not exist.
+func IsNotFound(err error) bool {
+	var requestErr *RequestError
+	return errors.As(err, &requestErr) && requestErr.StatusCode == http.StatusNotFound
+}
+
+// IsForbidden reports whether the request failed because the credentials lack the privileges to make it.
+func IsForbidden(err error) bool {
+	var requestErr *RequestError
+	return errors.As(err, &requestErr) && requestErr.StatusCode == http.StatusForbidden
+}
@@ -0,0 +1,47 @@
+package elastic
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientRequestErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/organizations/org1/members/u1":
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"errors":[{"code":"root.unauthorized"}]}`))
+		case "/_security/service/elastic/fleet-server/credential/token/t1":
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"found":false}`))
+		default:
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte(`{"found":true}`))
+		}
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	client := NewClient(server.Client(), "key", server.URL, "key", "org1").WithBaseURL(server.URL)
+
+	err := client.RemoveOrgMember(ctx, "org1", "u1")
+	var requestErr *RequestError
+	assert.True(t, errors.As(err, &requestErr))
+	assert.Equal(t, http.StatusForbidden, requestErr.StatusCode)
+	assert.Contains(t, err.Error(), "root.unauthorized")
+	assert.False(t, IsNotFound(err))
+	assert.True(t, IsForbidden(err))
+
+	found, err := client.DeleteServiceToken(ctx, "elastic", "fleet-server", "t1")
+	assert.Nil(t, err)
+	assert.False(t, found)
+
+	found, err = client.DeleteServiceToken(ctx, "elastic", "fleet-server", "t2")
+	assert.Nil(t, err)
+	assert.True(t, found)
+}
@@ -0,0 +1,53 @@
+package elastic
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+)
+
+// CloudEndpoints are the endpoints of an Elastic Cloud deployment encoded in its Cloud ID.
+type CloudEndpoints struct {
+	// Name is the deployment name the Cloud ID is labeled with.
+	Name          string
+	Elasticsearch string
+	Kibana        string
+}
+
+// ParseCloudID decodes a Cloud ID of the form <name>:<base64(host[:port]$elasticsearch-id[$kibana-id])>.
+// https://www.elastic.co/guide/en/cloud/current/ec-cloud-id.html
+func ParseCloudID(cloudID string) (*CloudEndpoints, error) {
+	name, encoded, ok := strings.Cut(cloudID, ":")
+	if !ok {
+		encoded = name
+		name = ""
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		decoded, err = base64.RawStdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cloud id: %w", err)
+		}
+	}
+
+	parts := strings.Split(string(decoded), "$")
+	if len(parts) < 2 || parts[0] == "" || parts[1] == "" {
+		return nil, fmt.Errorf("invalid cloud id: expected host and elasticsearch id")
+	}
+
+	host, port, hasPort := strings.Cut(parts[0], ":")
+	if hasPort {
+		port = ":" + port
+	}
+
+	endpoints := &CloudEndpoints{
+		Name:          name,
+		Elasticsearch: fmt.Sprintf("https://%s.%s%s", parts[1], host, port),
+	}
+	if len(parts) > 2 && parts[2] != "" {
+		endpoints.Kibana = fmt.Sprintf("https://%s.%s%s", parts[2], host, port)
+	}
+
+	return endpoints, nil
+}
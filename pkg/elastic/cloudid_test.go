@@ -0,0 +1,31 @@
+package elastic
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCloudID(t *testing.T) {
+	encode := func(s string) string {
+		return base64.StdEncoding.EncodeToString([]byte(s))
+	}
+
+	endpoints, err := ParseCloudID("prod:" + encode("us-central1.gcp.cloud.es.io$es123$kb456"))
+	assert.Nil(t, err)
+	assert.Equal(t, "prod", endpoints.Name)
+	assert.Equal(t, "https://es123.us-central1.gcp.cloud.es.io", endpoints.Elasticsearch)
+	assert.Equal(t, "https://kb456.us-central1.gcp.cloud.es.io", endpoints.Kibana)
+
+	endpoints, err = ParseCloudID("prod:" + encode("eastus2.azure.elastic-cloud.com:9243$es123"))
+	assert.Nil(t, err)
+	assert.Equal(t, "https://es123.eastus2.azure.elastic-cloud.com:9243", endpoints.Elasticsearch)
+	assert.Empty(t, endpoints.Kibana)
+
+	_, err = ParseCloudID("prod:not-base64!")
+	assert.NotNil(t, err)
+
+	_, err = ParseCloudID("prod:" + encode("us-central1.gcp.cloud.es.io"))
+	assert.NotNil(t, err)
+}
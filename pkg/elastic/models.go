@@ -1,5 +1,7 @@
 package elastic
 
+import "encoding/json"
+
 type DeploymentUser struct {
 	Username string      `json:"username"`
 	Roles    []string    `json:"roles"`
@@ -9,18 +11,180 @@ type DeploymentUser struct {
 	Metadata interface{} `json:"metadata"`
 }
 
+// DeploymentAPIKey is an API key of an elasticsearch deployment. Creation and expiration are in milliseconds since
+// the epoch, expiration is zero for keys that do not expire.
+type DeploymentAPIKey struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Creation    int64  `json:"creation"`
+	Expiration  int64  `json:"expiration"`
+	Invalidated bool   `json:"invalidated"`
+	Username    string `json:"username"`
+	Realm       string `json:"realm"`
+	RealmType   string `json:"realm_type"`
+}
+
+// ApplicationPrivilege is a privilege of an application registered with the elasticsearch privileges API, e.g. the
+// feature privileges of Kibana.
+type ApplicationPrivilege struct {
+	Application string   `json:"application"`
+	Name        string   `json:"name"`
+	Actions     []string `json:"actions"`
+}
+
+type InvalidateAPIKeyResponse struct {
+	InvalidatedAPIKeys           []string `json:"invalidated_api_keys"`
+	PreviouslyInvalidatedAPIKeys []string `json:"previously_invalidated_api_keys"`
+	ErrorCount                   int      `json:"error_count"`
+}
+
+// ServiceAccount is a built-in elasticsearch service account, e.g. elastic/fleet-server.
+type ServiceAccount struct {
+	RoleDescriptor DeploymentRole `json:"role_descriptor"`
+}
+
+// ServiceAccountCredentials are the service tokens of a service account. Index-backed tokens are stored in the
+// security index, file-backed tokens in the service_tokens file of each node.
+type ServiceAccountCredentials struct {
+	ServiceAccount   string                    `json:"service_account"`
+	Count            int                       `json:"count"`
+	Tokens           map[string]any            `json:"tokens"`
+	NodesCredentials ServiceAccountNodesTokens `json:"nodes_credentials"`
+}
+
+type ServiceAccountNodesTokens struct {
+	FileTokens map[string]struct {
+		Nodes []string `json:"nodes"`
+	} `json:"file_tokens"`
+}
+
 type DeploymentRole struct {
-	Cluster      []string      `json:"cluster"`
-	Applications []interface{} `json:"applications"`
-	RunAs        []string      `json:"run_as"`
+	Cluster      []string              `json:"cluster"`
+	Indices      []RoleIndexPrivileges `json:"indices"`
+	Applications []Applications        `json:"applications"`
+	RunAs        []string              `json:"run_as"`
+}
+
+// RoleIndexPrivileges are the privileges a role holds on the indices matching the names, which can be index names,
+// wildcard patterns or regular expressions.
+type RoleIndexPrivileges struct {
+	Names                  []string `json:"names"`
+	Privileges             []string `json:"privileges"`
+	AllowRestrictedIndices bool     `json:"allow_restricted_indices"`
 }
 
 type User struct {
-	Email          string `json:"email"`
-	MemberSince    string `json:"member_since"`
-	Name           string `json:"name"`
+	Email           string          `json:"email"`
+	MemberSince     string          `json:"member_since"`
+	Name            string          `json:"name"`
+	OrganizationID  string          `json:"organization_id"`
+	UserID          string          `json:"user_id"`
+	RoleAssignments RoleAssignments `json:"role_assignments"`
+}
+
+// RoleAssignments are the Elastic Cloud roles assigned to an organization member.
+// https://www.elastic.co/guide/en/cloud/current/ec-user-privileges.html
+type RoleAssignments struct {
+	Organization []OrganizationRoleAssignment `json:"organization,omitempty"`
+	Deployment   []DeploymentRoleAssignment   `json:"deployment,omitempty"`
+	Project      *ProjectRoleAssignments      `json:"project,omitempty"`
+}
+
+type OrganizationRoleAssignment struct {
 	OrganizationID string `json:"organization_id"`
-	UserID         string `json:"user_id"`
+	RoleID         string `json:"role_id"`
+}
+
+type DeploymentRoleAssignment struct {
+	OrganizationID string   `json:"organization_id"`
+	RoleID         string   `json:"role_id"`
+	All            bool     `json:"all,omitempty"`
+	DeploymentIDs  []string `json:"deployment_ids,omitempty"`
+}
+
+// ProjectRoleAssignments are the roles assigned for serverless projects, by project type.
+type ProjectRoleAssignments struct {
+	Elasticsearch []ProjectRoleAssignment `json:"elasticsearch,omitempty"`
+	Observability []ProjectRoleAssignment `json:"observability,omitempty"`
+	Security      []ProjectRoleAssignment `json:"security,omitempty"`
+}
+
+// ForType returns the role assignments for projects of the given type.
+func (p *ProjectRoleAssignments) ForType(projectType string) []ProjectRoleAssignment {
+	if p == nil {
+		return nil
+	}
+
+	switch projectType {
+	case ProjectTypeElasticsearch:
+		return p.Elasticsearch
+	case ProjectTypeObservability:
+		return p.Observability
+	case ProjectTypeSecurity:
+		return p.Security
+	default:
+		return nil
+	}
+}
+
+// NewProjectRoleAssignments returns the role assignments for projects of the given type.
+func NewProjectRoleAssignments(projectType string, assignments ...ProjectRoleAssignment) *ProjectRoleAssignments {
+	var rv ProjectRoleAssignments
+	switch projectType {
+	case ProjectTypeElasticsearch:
+		rv.Elasticsearch = assignments
+	case ProjectTypeObservability:
+		rv.Observability = assignments
+	case ProjectTypeSecurity:
+		rv.Security = assignments
+	}
+
+	return &rv
+}
+
+type ProjectRoleAssignment struct {
+	OrganizationID string   `json:"organization_id"`
+	RoleID         string   `json:"role_id"`
+	All            bool     `json:"all,omitempty"`
+	ProjectIDs     []string `json:"project_ids,omitempty"`
+}
+
+// PlatformUser is a user of an Elastic Cloud Enterprise installation.
+type PlatformUser struct {
+	UserName string               `json:"user_name"`
+	FullName string               `json:"full_name"`
+	Email    string               `json:"email"`
+	Builtin  bool                 `json:"builtin"`
+	Security PlatformUserSecurity `json:"security"`
+}
+
+type PlatformUserSecurity struct {
+	Enabled bool     `json:"enabled"`
+	Roles   []string `json:"roles"`
+}
+
+// Invitation is a pending invitation to join an Elastic organization.
+type Invitation struct {
+	Token           string          `json:"token"`
+	OrganizationID  string          `json:"organization_id"`
+	Email           string          `json:"email"`
+	Expired         bool            `json:"expired"`
+	CreatedAt       string          `json:"created_at"`
+	ExpiresAt       string          `json:"expires_at"`
+	AcceptedAt      string          `json:"accepted_at"`
+	RoleAssignments RoleAssignments `json:"role_assignments"`
+}
+
+// CloudAPIKey is an Elastic Cloud API key of an organization member.
+type CloudAPIKey struct {
+	ID              string          `json:"id"`
+	Description     string          `json:"description"`
+	CreationDate    string          `json:"creation_date"`
+	ExpirationDate  string          `json:"expiration_date"`
+	UserID          string          `json:"user_id"`
+	OrganizationID  string          `json:"organization_id"`
+	RoleAssignments RoleAssignments `json:"role_assignments"`
 }
 
 type Organization struct {
@@ -28,19 +192,94 @@ type Organization struct {
 	Name string `json:"name"`
 }
 
-type MappingRolesResponse struct {
-	Roles        []string `json:"roles,omitempty"`
-	Enabled      bool     `json:"enabled,omitempty"`
-	Rules        any      `json:"rules,omitempty"`
-	RuleTemplate any      `json:"role_templates,omitempty"`
-	Metadata     any      `json:"metadata,omitempty"`
+type Deployment struct {
+	ID        string              `json:"id"`
+	Name      string              `json:"name"`
+	Alias     string              `json:"alias"`
+	Resources DeploymentResources `json:"resources"`
+	Metadata  DeploymentMetadata  `json:"metadata"`
+}
+
+type DeploymentMetadata struct {
+	OrganizationID string `json:"organization_id"`
+}
+
+type DeploymentResources struct {
+	Elasticsearch []ElasticsearchResource `json:"elasticsearch"`
+}
+
+type ElasticsearchResource struct {
+	ID     string            `json:"id"`
+	RefID  string            `json:"ref_id"`
+	Region string            `json:"region"`
+	Info   ElasticsearchInfo `json:"info"`
 }
 
-type Rules struct {
-	Field map[string][]any `json:"field,omitempty"`
-	All   any              `json:"all,omitempty"`
+type ElasticsearchInfo struct {
+	PlanInfo struct {
+		Current struct {
+			Plan struct {
+				Elasticsearch struct {
+					Version string `json:"version"`
+				} `json:"elasticsearch"`
+			} `json:"plan"`
+		} `json:"current"`
+	} `json:"plan_info"`
+}
+
+// Elasticsearch returns the Elasticsearch cluster of the deployment, or nil if it has none.
+func (d *Deployment) Elasticsearch() *ElasticsearchResource {
+	if len(d.Resources.Elasticsearch) == 0 {
+		return nil
+	}
+
+	return &d.Resources.Elasticsearch[0]
+}
+
+// Serverless project types.
+const (
+	ProjectTypeElasticsearch = "elasticsearch"
+	ProjectTypeObservability = "observability"
+	ProjectTypeSecurity      = "security"
+)
+
+// ProjectTypes are all serverless project types.
+var ProjectTypes = []string{ProjectTypeElasticsearch, ProjectTypeObservability, ProjectTypeSecurity}
+
+// Project is an Elastic Cloud Serverless project.
+type Project struct {
+	ID        string           `json:"id"`
+	Name      string           `json:"name"`
+	Alias     string           `json:"alias"`
+	Type      string           `json:"type"`
+	RegionID  string           `json:"region_id"`
+	CloudID   string           `json:"cloud_id"`
+	Endpoints ProjectEndpoints `json:"endpoints"`
+	Metadata  ProjectMetadata  `json:"metadata"`
+}
+
+type ProjectEndpoints struct {
+	Elasticsearch string `json:"elasticsearch"`
+	Kibana        string `json:"kibana"`
+}
+
+type ProjectMetadata struct {
+	OrganizationID string `json:"organization_id"`
+	CreatedAt      string `json:"created_at"`
+}
+
+// MappingRolesResponse is a role mapping. It is also the body role mappings are written back with, so role templates
+// and metadata are kept as they were read.
+type MappingRolesResponse struct {
+	Roles        []string         `json:"roles,omitempty"`
+	Enabled      bool             `json:"enabled"`
+	Rules        *RoleMappingRule `json:"rules,omitempty"`
+	RuleTemplate json.RawMessage  `json:"role_templates,omitempty"`
+	Metadata     json.RawMessage  `json:"metadata,omitempty"`
 }
 
+// Rule, MappingRolesBody and Field are the body of UpdateUserMappingRole, which maps users by username only. The
+// connector writes role mappings back as MappingRolesResponse instead, which keeps their whole rules.
 type Rule struct {
 	Field Field `json:"field,omitempty"`
 }
@@ -0,0 +1,273 @@
+package elastic
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"slices"
+)
+
+// RoleMappingRule is a rule of a role mapping. Exactly one of Any, All, Except and Field is set.
+// https://www.elastic.co/guide/en/elasticsearch/reference/current/role-mapping-resources.html
+type RoleMappingRule struct {
+	// Any matches when at least one of the rules matches.
+	Any []RoleMappingRule
+	// All matches when every rule matches.
+	All []RoleMappingRule
+	// Except matches when the rule does not match. It is only valid within an all rule.
+	Except *RoleMappingRule
+	// Field matches when the user attribute matches one of the values.
+	Field *RoleMappingFieldRule
+}
+
+// RoleMappingFieldRule matches a user attribute, e.g. username, dn, groups, realm.name or metadata.*, against
+// values. String values may be exact values, wildcard patterns or /regular expressions/.
+type RoleMappingFieldRule struct {
+	Name   string
+	Values []any
+
+	// single reports whether the rule was read with a single value rather than an array, so that it is written back
+	// as it was.
+	single bool
+}
+
+// NewRoleMappingFieldRule returns a field rule matching the attribute against any of the values.
+func NewRoleMappingFieldRule(name string, values ...any) *RoleMappingFieldRule {
+	return &RoleMappingFieldRule{Name: name, Values: values}
+}
+
+func (r RoleMappingRule) MarshalJSON() ([]byte, error) {
+	switch {
+	case r.Any != nil:
+		return json.Marshal(map[string][]RoleMappingRule{"any": r.Any})
+	case r.All != nil:
+		return json.Marshal(map[string][]RoleMappingRule{"all": r.All})
+	case r.Except != nil:
+		return json.Marshal(map[string]*RoleMappingRule{"except": r.Except})
+	case r.Field != nil:
+		return json.Marshal(map[string]*RoleMappingFieldRule{"field": r.Field})
+	default:
+		return nil, fmt.Errorf("empty role mapping rule")
+	}
+}
+
+func (r *RoleMappingRule) UnmarshalJSON(data []byte) error {
+	var rule map[string]json.RawMessage
+	if err := json.Unmarshal(data, &rule); err != nil {
+		return err
+	}
+	if len(rule) != 1 {
+		return fmt.Errorf("role mapping rule must have exactly one of any, all, except or field, got %d keys", len(rule))
+	}
+
+	*r = RoleMappingRule{}
+	for kind, value := range rule {
+		switch kind {
+		case "any":
+			r.Any = []RoleMappingRule{}
+			return json.Unmarshal(value, &r.Any)
+		case "all":
+			r.All = []RoleMappingRule{}
+			return json.Unmarshal(value, &r.All)
+		case "except":
+			r.Except = &RoleMappingRule{}
+			return json.Unmarshal(value, r.Except)
+		case "field":
+			r.Field = &RoleMappingFieldRule{}
+			return json.Unmarshal(value, r.Field)
+		default:
+			return fmt.Errorf("unknown role mapping rule %q", kind)
+		}
+	}
+
+	return nil
+}
+
+func (f RoleMappingFieldRule) MarshalJSON() ([]byte, error) {
+	if f.single && len(f.Values) == 1 {
+		return json.Marshal(map[string]any{f.Name: f.Values[0]})
+	}
+
+	values := f.Values
+	if values == nil {
+		values = []any{}
+	}
+
+	return json.Marshal(map[string][]any{f.Name: values})
+}
+
+func (f *RoleMappingFieldRule) UnmarshalJSON(data []byte) error {
+	var field map[string]json.RawMessage
+	if err := json.Unmarshal(data, &field); err != nil {
+		return err
+	}
+	if len(field) != 1 {
+		return fmt.Errorf("role mapping field rule must have exactly one field, got %d", len(field))
+	}
+
+	*f = RoleMappingFieldRule{}
+	for name, value := range field {
+		f.Name = name
+
+		// Numbers are kept as json.Number so that they are written back unchanged.
+		decoder := json.NewDecoder(bytes.NewReader(value))
+		decoder.UseNumber()
+
+		var values any
+		if err := decoder.Decode(&values); err != nil {
+			return err
+		}
+
+		if list, ok := values.([]any); ok {
+			f.Values = list
+		} else {
+			f.Values = []any{values}
+			f.single = true
+		}
+	}
+
+	return nil
+}
+
+// FieldValues returns the string values the rule matches the attribute against where a match grants the mapping,
+// i.e. not within except rules. Values within except rules are skipped even where nested except rules grant the mapping
+// again, as WithFieldValue and WithoutFieldValue never change them. Conditions on other attributes are not evaluated.
+func (r RoleMappingRule) FieldValues(name string) []string {
+	var rv []string
+	r.walk(false, func(field *RoleMappingFieldRule, excepted bool) {
+		if excepted || field.Name != name {
+			return
+		}
+
+		for _, value := range field.Values {
+			if s, ok := value.(string); ok && !slices.Contains(rv, s) {
+				rv = append(rv, s)
+			}
+		}
+	})
+
+	return rv
+}
+
+// walk calls fn for every field rule within the rule with whether it is within an except rule.
+func (r RoleMappingRule) walk(excepted bool, fn func(field *RoleMappingFieldRule, excepted bool)) {
+	for _, rule := range r.Any {
+		rule.walk(excepted, fn)
+	}
+	for _, rule := range r.All {
+		rule.walk(excepted, fn)
+	}
+	if r.Except != nil {
+		r.Except.walk(true, fn)
+	}
+	if r.Field != nil {
+		fn(r.Field, excepted)
+	}
+}
+
+// WithFieldValue returns the rule extended to match the attribute value under the same conditions as the values
+// already listed next to it, so that e.g. the realm condition of an all rule still applies to it. The value is added
+// to a field rule of the attribute at the top of the rule, or else within the first of its any and all rules that has
+// one; field rules within except rules are never used. It reports false when the rule has no such field rule, as
+// adding one would match the value without the conditions of the rule.
+func (r RoleMappingRule) WithFieldValue(name string, value any) (RoleMappingRule, bool) {
+	if r.Field != nil {
+		if r.Field.Name != name {
+			return r, false
+		}
+		return RoleMappingRule{Field: r.Field.withValue(value)}, true
+	}
+
+	var rules []RoleMappingRule
+	switch {
+	case r.Any != nil:
+		rules = r.Any
+	case r.All != nil:
+		rules = r.All
+	default:
+		return r, false
+	}
+
+	for i, rule := range rules {
+		if rule.Field != nil && rule.Field.Name == name {
+			return r.withRule(i, RoleMappingRule{Field: rule.Field.withValue(value)}), true
+		}
+	}
+	for i, rule := range rules {
+		if rule.Field != nil || rule.Except != nil {
+			continue
+		}
+		if extended, ok := rule.WithFieldValue(name, value); ok {
+			return r.withRule(i, extended), true
+		}
+	}
+
+	return r, false
+}
+
+// withRule returns a copy of the any or all rule with its i-th rule replaced.
+func (r RoleMappingRule) withRule(i int, rule RoleMappingRule) RoleMappingRule {
+	if r.Any != nil {
+		rules := slices.Clone(r.Any)
+		rules[i] = rule
+		return RoleMappingRule{Any: rules}
+	}
+
+	rules := slices.Clone(r.All)
+	rules[i] = rule
+	return RoleMappingRule{All: rules}
+}
+
+// WithoutFieldValue returns the rule without the attribute value where a match grants the mapping, i.e. not within
+// except rules. Branches of any rules left matching nobody are removed. It reports false when the whole rule is left
+// matching nobody, which can't be written back.
+func (r RoleMappingRule) WithoutFieldValue(name string, value any) (RoleMappingRule, bool) {
+	switch {
+	case r.Field != nil:
+		if r.Field.Name != name || !slices.Contains(r.Field.Values, value) {
+			return r, true
+		}
+
+		field := *r.Field
+		field.Values = slices.DeleteFunc(slices.Clone(field.Values), func(v any) bool { return v == value })
+
+		return RoleMappingRule{Field: &field}, len(field.Values) > 0
+
+	case r.Any != nil:
+		if len(r.Any) == 0 {
+			return r, true
+		}
+
+		rules := []RoleMappingRule{}
+		for _, rule := range r.Any {
+			if rule, ok := rule.WithoutFieldValue(name, value); ok {
+				rules = append(rules, rule)
+			}
+		}
+
+		return RoleMappingRule{Any: rules}, len(rules) > 0
+
+	case r.All != nil:
+		rules := make([]RoleMappingRule, 0, len(r.All))
+		for _, rule := range r.All {
+			rule, ok := rule.WithoutFieldValue(name, value)
+			if !ok {
+				return r, false
+			}
+			rules = append(rules, rule)
+		}
+
+		return RoleMappingRule{All: rules}, true
+
+	default:
+		return r, true
+	}
+}
+
+func (f *RoleMappingFieldRule) withValue(value any) *RoleMappingFieldRule {
+	if slices.Contains(f.Values, value) {
+		return f
+	}
+
+	return &RoleMappingFieldRule{Name: f.Name, Values: append(slices.Clone(f.Values), value)}
+}
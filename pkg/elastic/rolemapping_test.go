@@ -0,0 +1,115 @@
+package elastic
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRoleMappingRuleRoundTrip(t *testing.T) {
+	rules := []string{
+		`{"field":{"username":"alice"}}`,
+		`{"field":{"username":["alice","bob"]}}`,
+		`{"field":{"metadata.level":3}}`,
+		`{"field":{"metadata.active":[true,null,1.5]}}`,
+		`{"any":[]}`,
+		`{"any":[{"field":{"username":"/a.*/"}},{"field":{"groups":"cn=admins,dc=example,dc=com"}}]}`,
+		`{"all":[{"field":{"realm.name":"saml1"}},{"except":{"field":{"dn":"*,ou=contractors,dc=example,dc=com"}}}]}`,
+	}
+
+	for _, rule := range rules {
+		var parsed RoleMappingRule
+		assert.Nil(t, json.Unmarshal([]byte(rule), &parsed), rule)
+
+		data, err := json.Marshal(parsed)
+		assert.Nil(t, err, rule)
+		assert.JSONEq(t, rule, string(data))
+	}
+
+	var parsed RoleMappingRule
+	assert.NotNil(t, json.Unmarshal([]byte(`{"field":{"username":"alice"},"any":[]}`), &parsed))
+	assert.NotNil(t, json.Unmarshal([]byte(`{"none":{}}`), &parsed))
+}
+
+func TestRoleMappingRuleFieldValues(t *testing.T) {
+	var rule RoleMappingRule
+	err := json.Unmarshal([]byte(`{"any":[
+		{"field":{"username":["alice","bob"]}},
+		{"all":[{"field":{"username":"carol"}},{"except":{"field":{"username":"dave"}}}]},
+		{"all":[{"except":{"any":[{"except":{"field":{"username":"erin"}}}]}}]},
+		{"field":{"groups":"admins"}}
+	]}`), &rule)
+	assert.Nil(t, err)
+
+	assert.Equal(t, []string{"alice", "bob", "carol"}, rule.FieldValues("username"))
+	assert.Equal(t, []string{"admins"}, rule.FieldValues("groups"))
+	assert.Empty(t, rule.FieldValues("dn"))
+}
+
+func TestRoleMappingRuleDoubleExcept(t *testing.T) {
+	rule := `{"all":[{"field":{"realm.name":"saml1"}},{"except":{"except":{"field":{"username":"erin"}}}}]}`
+
+	var parsed RoleMappingRule
+	assert.Nil(t, json.Unmarshal([]byte(rule), &parsed))
+
+	// Values within except rules are neither reported nor changed, even where a second except rule grants the mapping.
+	assert.Empty(t, parsed.FieldValues("username"))
+
+	removed, ok := parsed.WithoutFieldValue("username", "erin")
+	assert.True(t, ok)
+	assert.Equal(t, parsed, removed)
+
+	_, ok = parsed.WithFieldValue("username", "erin")
+	assert.False(t, ok)
+}
+
+func TestRoleMappingRuleEditFieldValue(t *testing.T) {
+	parse := func(rule string) RoleMappingRule {
+		var parsed RoleMappingRule
+		assert.Nil(t, json.Unmarshal([]byte(rule), &parsed), rule)
+		return parsed
+	}
+	marshal := func(rule RoleMappingRule) string {
+		data, err := json.Marshal(rule)
+		assert.Nil(t, err)
+		return string(data)
+	}
+
+	added, ok := parse(`{"field":{"username":"alice"}}`).WithFieldValue("username", "bob")
+	assert.True(t, ok)
+	assert.JSONEq(t, `{"field":{"username":["alice","bob"]}}`, marshal(added))
+
+	added, ok = parse(`{"any":[{"field":{"groups":"admins"}},{"field":{"username":"alice"}}]}`).WithFieldValue("username", "bob")
+	assert.True(t, ok)
+	assert.JSONEq(t, `{"any":[{"field":{"groups":"admins"}},{"field":{"username":["alice","bob"]}}]}`, marshal(added))
+
+	// The realm condition of the all rule still applies to the added user.
+	original := parse(`{"all":[{"field":{"realm.name":"saml1"}},{"field":{"username":"alice"}}]}`)
+	added, ok = original.WithFieldValue("username", "bob")
+	assert.True(t, ok)
+	assert.JSONEq(t, `{"all":[{"field":{"realm.name":"saml1"}},{"field":{"username":["alice","bob"]}}]}`, marshal(added))
+	assert.JSONEq(t, `{"all":[{"field":{"realm.name":"saml1"}},{"field":{"username":"alice"}}]}`, marshal(original))
+
+	added, ok = parse(`{"any":[{"field":{"groups":"admins"}},{"all":[{"field":{"realm.name":"saml1"}},{"except":{"field":{"username":"carol"}}},{"any":[{"field":{"username":"alice"}}]}]}]}`).WithFieldValue("username", "bob")
+	assert.True(t, ok)
+	assert.JSONEq(t, `{"any":[{"field":{"groups":"admins"}},{"all":[{"field":{"realm.name":"saml1"}},{"except":{"field":{"username":"carol"}}},{"any":[{"field":{"username":["alice","bob"]}}]}]}]}`, marshal(added))
+
+	// Rules without a username outside except rules have no place the user could be added to safely.
+	_, ok = parse(`{"all":[{"field":{"realm.name":"saml1"}},{"except":{"field":{"username":"carol"}}}]}`).WithFieldValue("username", "bob")
+	assert.False(t, ok)
+	_, ok = parse(`{"any":[{"field":{"groups":"admins"}}]}`).WithFieldValue("username", "bob")
+	assert.False(t, ok)
+
+	added = parse(`{"any":[{"all":[{"field":{"realm.name":"saml1"}},{"field":{"username":"alice"}}]},{"field":{"username":"bob"}}]}`)
+	removed, ok := added.WithoutFieldValue("username", "alice")
+	assert.True(t, ok)
+	assert.JSONEq(t, `{"any":[{"field":{"username":"bob"}}]}`, marshal(removed))
+
+	removed, ok = parse(`{"all":[{"field":{"username":["alice","bob"]}},{"except":{"field":{"username":"alice"}}}]}`).WithoutFieldValue("username", "alice")
+	assert.True(t, ok)
+	assert.JSONEq(t, `{"all":[{"field":{"username":["bob"]}},{"except":{"field":{"username":"alice"}}}]}`, marshal(removed))
+
+	_, ok = parse(`{"field":{"username":"alice"}}`).WithoutFieldValue("username", "alice")
+	assert.False(t, ok)
+}